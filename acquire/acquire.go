@@ -0,0 +1,168 @@
+// Package acquire find the ways to obtain a publication through its
+// acquisition links and download it
+package acquire
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/opds2"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// ErrNoPath is returned when no acquisition path fits the preferences
+var ErrNoPath = errors.New("acquire: no acquisition path")
+
+// Path is one way to acquire a publication: an acquisition link and the
+// chain of media types obtained from it, the type of the link first and
+// the type of the final file last
+type Path struct {
+	Link  *opds2.Link
+	Rel   string
+	Types []string
+}
+
+// Preferences select and rank the acquisition paths
+type Preferences struct {
+	// Types are the media types the client can open, best first
+	Types []string
+	// Intermediate are the media types the client can process to reach
+	// the final file, like an LCP license, paths through other types are
+	// skipped
+	Intermediate []string
+	// Rels are the acquisition relations accepted, best first
+	Rels []string
+}
+
+// DefaultPreferences accept free EPUB and PDF files, EPUB first
+var DefaultPreferences = Preferences{
+	Types: []string{mediatype.EPUB, mediatype.PDF},
+	Rels:  []string{rel.AcquisitionOpenAccess, rel.Acquisition, rel.AcquisitionBorrow, rel.AcquisitionSample},
+}
+
+// MediaType return the type of the file obtained at the end of the path
+func (p Path) MediaType() string {
+	if len(p.Types) == 0 {
+		return ""
+	}
+	return p.Types[len(p.Types)-1]
+}
+
+// Direct check if the link gives the final file, without intermediate
+// document
+func (p Path) Direct() bool {
+	return len(p.Types) == 1
+}
+
+// Price return the price of the acquisition link in the first of the
+// currencies it is sold in, nil when it has none
+func (p Path) Price(currencies ...string) *opds2.Price {
+	if p.Link.Properties == nil {
+		return nil
+	}
+	return p.Link.Properties.Price.Preferred(currencies...)
+}
+
+// Paths enumerate the acquisition paths of a publication, one per leaf of
+// the indirect acquisition tree of each acquisition link
+func Paths(pub *opds2.Publication) []Path {
+	var paths []Path
+	for _, l := range pub.Links.Acquisitions() {
+		r := acquisitionRel(l)
+		var ias []opds2.IndirectAcquisition
+		if l.Properties != nil {
+			ias = l.Properties.IndirectAcquisition
+		}
+		if len(ias) == 0 {
+			paths = append(paths, Path{Link: l, Rel: r, Types: []string{l.TypeLink}})
+			continue
+		}
+		var walk func(chain []string, ias []opds2.IndirectAcquisition)
+		walk = func(chain []string, ias []opds2.IndirectAcquisition) {
+			for _, ia := range ias {
+				c := append(append([]string(nil), chain...), ia.TypeAcquisition)
+				if len(ia.Child) == 0 {
+					paths = append(paths, Path{Link: l, Rel: r, Types: c})
+					continue
+				}
+				walk(c, ia.Child)
+			}
+		}
+		walk([]string{l.TypeLink}, ias)
+	}
+	return paths
+}
+
+// Best return the best path of the publication for the preferences
+func Best(pub *opds2.Publication, prefs Preferences) (Path, error) {
+	paths := prefs.Filter(Paths(pub))
+	if len(paths) == 0 {
+		return Path{}, ErrNoPath
+	}
+	return paths[0], nil
+}
+
+// Filter return the paths accepted by the preferences, best first: by
+// relation, then media type, then shortest chain
+func (prefs Preferences) Filter(paths []Path) []Path {
+	var res []Path
+	for _, p := range paths {
+		if prefs.accept(p) {
+			res = append(res, p)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		ri, rj := index(prefs.Rels, res[i].Rel), index(prefs.Rels, res[j].Rel)
+		if ri != rj {
+			return ri < rj
+		}
+		ti, tj := matchIndex(prefs.Types, res[i].MediaType()), matchIndex(prefs.Types, res[j].MediaType())
+		if ti != tj {
+			return ti < tj
+		}
+		return len(res[i].Types) < len(res[j].Types)
+	})
+	return res
+}
+
+func (prefs Preferences) accept(p Path) bool {
+	if index(prefs.Rels, p.Rel) < 0 || matchIndex(prefs.Types, p.MediaType()) < 0 {
+		return false
+	}
+	for _, t := range p.Types[:len(p.Types)-1] {
+		if matchIndex(prefs.Intermediate, t) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// acquisitionRel return the acquisition relation of a link
+func acquisitionRel(l *opds2.Link) string {
+	for _, r := range l.Rel {
+		if rel.IsAcquisition(r) {
+			return r
+		}
+	}
+	return ""
+}
+
+func index(values []string, v string) int {
+	for i, o := range values {
+		if o == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchIndex return the index of the first pattern matching mt
+func matchIndex(patterns []string, mt string) int {
+	for i, p := range patterns {
+		if mediatype.Matches(p, mt) {
+			return i
+		}
+	}
+	return -1
+}
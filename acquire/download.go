@@ -0,0 +1,239 @@
+package acquire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/opds2"
+)
+
+// ErrContentType is returned when the server answer with another type than
+// the one of the link, like a login page instead of a book
+var ErrContentType = errors.New("acquire: unexpected content type")
+
+// Downloader fetch the resource of an acquisition path
+type Downloader struct {
+	// Client used for the requests, http.DefaultClient when nil
+	Client *http.Client
+	// Header is added to every request, for authentication
+	Header http.Header
+	// Progress is called as the file is written with the number of bytes
+	// written and the total size, -1 when unknown
+	Progress func(written int64, total int64)
+}
+
+// Fetch download the best path of the publication for the preferences to
+// dest
+func (d *Downloader) Fetch(ctx context.Context, pub *opds2.Publication, prefs Preferences, dest string) (Path, error) {
+	p, err := Best(pub, prefs)
+	if err != nil {
+		return p, err
+	}
+	return p, d.Download(ctx, p, dest)
+}
+
+// Download write the resource of the acquisition link of the path to dest,
+// for an indirect path it is the first document of the chain, like an LCP
+// license, that the caller process. The file is written to dest.part first
+// and an interrupted download is resumed with a range request, made
+// conditional on the ETag or Last-Modified of the first response saved in
+// dest.part.validator, the download restart from scratch when the server
+// doesn't answer with the expected range.
+func (d *Downloader) Download(ctx context.Context, p Path, dest string) error {
+	return d.download(ctx, p, dest, true)
+}
+
+func (d *Downloader) download(ctx context.Context, p Path, dest string, resume bool) error {
+	part := dest + ".part"
+	validatorPath := part + ".validator"
+	var offset int64
+	var validator string
+	if fi, err := os.Stat(part); err == nil && resume {
+		offset = fi.Size()
+		if b, err := os.ReadFile(validatorPath); err == nil {
+			validator = strings.TrimSpace(string(b))
+		}
+	}
+	if offset > 0 && validator == "" {
+		// without validator the part file could be from another version
+		offset = 0
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Link.Href, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range d.Header {
+		req.Header[k] = v
+	}
+	if p.Link.TypeLink != "" {
+		req.Header.Set("Accept", p.Link.TypeLink+", */*;q=0.1")
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", validator)
+	}
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	restart := func() error {
+		res.Body.Close()
+		os.Remove(part)
+		os.Remove(validatorPath)
+		if !resume {
+			return fmt.Errorf("acquire: %s: unexpected range %q", p.Link.Href, res.Header.Get("Content-Range"))
+		}
+		return d.download(ctx, p, dest, false)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	total := int64(-1)
+	switch res.StatusCode {
+	case http.StatusOK:
+		offset = 0
+		flags |= os.O_TRUNC
+		if res.ContentLength >= 0 {
+			total = res.ContentLength
+		}
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(res.Header.Get("Content-Range"))
+		if !ok || offset == 0 || start != offset {
+			return restart()
+		}
+		flags |= os.O_APPEND
+		total = size
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset > 0 {
+			// the part file is complete when its size is the total of the
+			// resource, given as bytes */total
+			if _, size, ok := parseContentRange(res.Header.Get("Content-Range")); ok && size == offset {
+				os.Remove(validatorPath)
+				return os.Rename(part, dest)
+			}
+			return restart()
+		}
+		fallthrough
+	default:
+		return fmt.Errorf("acquire: %s: %s", p.Link.Href, res.Status)
+	}
+	if err := checkContentType(p.Link.TypeLink, res.Header.Get("Content-Type")); err != nil {
+		return err
+	}
+
+	if offset == 0 {
+		if v := responseValidator(res); v != "" {
+			if err := os.WriteFile(validatorPath, []byte(v), 0o644); err != nil {
+				return err
+			}
+		} else {
+			os.Remove(validatorPath)
+		}
+	}
+
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	w := &progressWriter{w: f, written: offset, total: total, fn: d.Progress}
+	if _, err := io.Copy(w, res.Body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if total >= 0 && w.written != total {
+		return fmt.Errorf("acquire: %s: got %d bytes of %d", p.Link.Href, w.written, total)
+	}
+	os.Remove(validatorPath)
+	return os.Rename(part, dest)
+}
+
+// responseValidator return the strong ETag of the response, or its
+// Last-Modified date, to send as If-Range when resuming
+func responseValidator(res *http.Response) string {
+	if etag := res.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return res.Header.Get("Last-Modified")
+}
+
+// parseContentRange read a Content-Range header, bytes start-end/total or
+// bytes */total, the total is -1 when given as *
+func parseContentRange(s string) (start int64, total int64, ok bool) {
+	rng, ok := strings.CutPrefix(strings.TrimSpace(s), "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rng, size, ok := strings.Cut(rng, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, false
+		}
+		total = n
+	}
+	if rng == "*" {
+		return 0, total, total >= 0
+	}
+	first, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+// checkContentType accept the expected type, or a generic binary type that
+// some servers send for every file
+func checkContentType(expected string, got string) error {
+	if expected == "" || got == "" {
+		return nil
+	}
+	base := mediatype.Base(got)
+	if base == mediatype.OctetStream || base == "binary/octet-stream" {
+		return nil
+	}
+	if mediatype.Base(expected) != base {
+		return fmt.Errorf("%w %s, expected %s", ErrContentType, got, expected)
+	}
+	return nil
+}
+
+type progressWriter struct {
+	w       io.Writer
+	written int64
+	total   int64
+	fn      func(int64, int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if p.fn != nil {
+		p.fn(p.written, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,349 @@
+// Package calibre read a Calibre library directory and turn its books in
+// OPDS publications
+package calibre
+
+import (
+	"database/sql"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	// sqlite driver used to read metadata.db
+	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/text/language"
+
+	"github.com/ohzqq/libopds2-go/library"
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/opds2"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// Library is an opened Calibre library
+type Library struct {
+	Dir     string
+	BaseURL string
+	db      *sql.DB
+}
+
+// Book is a book of the Calibre library with the files of its formats
+// relative to the library directory
+type Book struct {
+	ID          int
+	Path        string
+	Added       time.Time
+	Formats     map[string]string
+	HasCover    bool
+	Publication opds2.Publication
+	// seriesIndex is the position of the book in its series, Calibre set
+	// it even for the books without series
+	seriesIndex float64
+}
+
+// Open open the metadata.db of the Calibre library in dir read only, the
+// files of the library are linked from baseURL
+func Open(dir string, baseURL string) (*Library, error) {
+	dbPath := filepath.Join(dir, "metadata.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Library{
+		Dir:     dir,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		db:      db,
+	}, nil
+}
+
+// Close close the database
+func (lib *Library) Close() error {
+	return lib.db.Close()
+}
+
+// Publications return every book of the library as a publication
+func (lib *Library) Publications() ([]opds2.Publication, error) {
+	books, err := lib.Books()
+	if err != nil {
+		return nil, err
+	}
+	pubs := make([]opds2.Publication, 0, len(books))
+	for _, b := range books {
+		pubs = append(pubs, b.Publication)
+	}
+	return pubs, nil
+}
+
+// Catalog return a library.Library holding the books of the Calibre
+// library, ready to be exported or served
+func (lib *Library) Catalog() (*library.Library, error) {
+	books, err := lib.Books()
+	if err != nil {
+		return nil, err
+	}
+	cat := library.New(lib.Dir, lib.BaseURL)
+	cat.Importers = nil
+	for _, b := range books {
+		book := &library.Book{Publication: b.Publication, Added: b.Added}
+		for _, f := range b.Formats {
+			book.Files = append(book.Files, f)
+		}
+		sort.Strings(book.Files)
+		cat.Add(book)
+	}
+	return cat, nil
+}
+
+// Books read every book of the library with its metadata
+func (lib *Library) Books() ([]*Book, error) {
+	rows, err := lib.db.Query(`SELECT id, title, path, has_cover, timestamp, pubdate,
+		last_modified, series_index, uuid FROM books ORDER BY sort`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var books []*Book
+	byID := make(map[int]*Book)
+	for rows.Next() {
+		var (
+			b                          Book
+			title, p                   string
+			uuid                       sql.NullString
+			added, published, modified sql.NullString
+			seriesIndex                sql.NullFloat64
+		)
+		if err := rows.Scan(&b.ID, &title, &p, &b.HasCover, &added, &published, &modified, &seriesIndex, &uuid); err != nil {
+			return nil, err
+		}
+		b.Path = p
+		b.Formats = make(map[string]string)
+		m := &b.Publication.Metadata
+		m.Title.SingleString = title
+		if uuid.Valid && uuid.String != "" {
+			m.Identifier = "urn:uuid:" + uuid.String
+		}
+		if t, ok := parseTime(added.String); ok {
+			b.Added = t
+		}
+		if t, ok := parseTime(modified.String); ok {
+			m.Modified = &t
+		}
+		if t, ok := parseTime(published.String); ok && t.Year() > 101 {
+			m.PublicationDate = &t
+		}
+		b.seriesIndex = seriesIndex.Float64
+		books = append(books, &b)
+		byID[b.ID] = &b
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	loaders := []func(map[int]*Book) error{
+		lib.loadAuthors,
+		lib.loadSeries,
+		lib.loadTags,
+		lib.loadPublishers,
+		lib.loadLanguages,
+		lib.loadIdentifiers,
+		lib.loadComments,
+		lib.loadFormats,
+	}
+	for _, load := range loaders {
+		if err := load(byID); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, b := range books {
+		lib.addLinks(b)
+	}
+
+	return books, nil
+}
+
+func (lib *Library) loadAuthors(books map[int]*Book) error {
+	return lib.each(`SELECT l.book, a.name, a.sort, a.link FROM books_authors_link l
+		JOIN authors a ON a.id = l.author ORDER BY l.id`, func(b *Book, values []sql.NullString) {
+		c := &opds2.Contributor{SortAs: values[1].String}
+		c.Name.SingleString = values[0].String
+		if values[2].String != "" {
+			c.Links = append(c.Links, &opds2.Link{Href: values[2].String})
+		}
+		b.Publication.Metadata.Author = append(b.Publication.Metadata.Author, c)
+	}, books)
+}
+
+func (lib *Library) loadSeries(books map[int]*Book) error {
+	return lib.each(`SELECT l.book, s.name, s.sort FROM books_series_link l
+		JOIN series s ON s.id = l.series ORDER BY l.id`, func(b *Book, values []sql.NullString) {
+		if values[0].String == "" {
+			return
+		}
+		col := b.Publication.BelongsToSeries(values[0].String)
+		col.SortAs = values[1].String
+		col.Position = b.seriesIndex
+	}, books)
+}
+
+func (lib *Library) loadTags(books map[int]*Book) error {
+	return lib.each(`SELECT l.book, t.name FROM books_tags_link l
+		JOIN tags t ON t.id = l.tag ORDER BY t.name`, func(b *Book, values []sql.NullString) {
+		b.Publication.Metadata.Subject = append(b.Publication.Metadata.Subject, &opds2.Subject{Name: values[0].String})
+	}, books)
+}
+
+func (lib *Library) loadPublishers(books map[int]*Book) error {
+	return lib.each(`SELECT l.book, p.name FROM books_publishers_link l
+		JOIN publishers p ON p.id = l.publisher`, func(b *Book, values []sql.NullString) {
+		c := &opds2.Contributor{}
+		c.Name.SingleString = values[0].String
+		b.Publication.Metadata.Publisher = append(b.Publication.Metadata.Publisher, c)
+	}, books)
+}
+
+func (lib *Library) loadLanguages(books map[int]*Book) error {
+	return lib.each(`SELECT l.book, g.lang_code FROM books_languages_link l
+		JOIN languages g ON g.id = l.lang_code ORDER BY l.item_order`, func(b *Book, values []sql.NullString) {
+		code := values[0].String
+		if tag, err := language.Parse(code); err == nil {
+			code = tag.String()
+		}
+		b.Publication.Metadata.Language = append(b.Publication.Metadata.Language, code)
+	}, books)
+}
+
+// loadIdentifiers prefer a valid ISBN to the Calibre uuid as identifier,
+// the lowest one when the book has several, the uuid, the other ISBN, the
+// DOI and the ASIN are kept as alternate identifiers, the invalid ones are
+// dropped
+func (lib *Library) loadIdentifiers(books map[int]*Book) error {
+	return lib.each(`SELECT book, type, val FROM identifiers ORDER BY book, type, val`, func(b *Book, values []sql.NullString) {
+		m := &b.Publication.Metadata
+		val := strings.TrimSpace(values[1].String)
+		var urn string
+		switch strings.ToLower(values[0].String) {
+		case "isbn":
+			urn = "urn:isbn:" + val
+		case "doi":
+			urn = "urn:doi:" + val
+		case "amazon", "asin", "mobi-asin":
+			urn = "urn:asin:" + val
+		default:
+			return
+		}
+		id, err := opds2.ParseIdentifier(urn)
+		if err != nil {
+			return
+		}
+		if id.Type == opds2.IdentifierISBN && !strings.HasPrefix(m.Identifier, "urn:isbn:") {
+			uuid := m.Identifier
+			m.Identifier = id.URN()
+			m.AddAltIdentifier(uuid)
+			return
+		}
+		m.AddAltIdentifier(id.URN())
+	}, books)
+}
+
+func (lib *Library) loadComments(books map[int]*Book) error {
+	return lib.each(`SELECT book, text FROM comments`, func(b *Book, values []sql.NullString) {
+		b.Publication.Metadata.Description = values[0].String
+	}, books)
+}
+
+func (lib *Library) loadFormats(books map[int]*Book) error {
+	return lib.each(`SELECT book, format, name FROM data ORDER BY format`, func(b *Book, values []sql.NullString) {
+		format := strings.ToLower(values[0].String)
+		b.Formats[format] = path.Join(b.Path, values[1].String+"."+format)
+	}, books)
+}
+
+// each run a query whose first column is the book id and call fn with the
+// other columns for every known book
+func (lib *Library) each(query string, fn func(*Book, []sql.NullString), books map[int]*Book) error {
+	rows, err := lib.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int
+		values := make([]sql.NullString, len(cols)-1)
+		dest := []any{&id}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		if b, ok := books[id]; ok {
+			fn(b, values)
+		}
+	}
+	return rows.Err()
+}
+
+// addLinks add the cover and an acquisition link for every format
+func (lib *Library) addLinks(b *Book) {
+	pub := &b.Publication
+	if b.HasCover {
+		pub.AddImage(map[string]any{
+			"href": lib.fileURL(path.Join(b.Path, "cover.jpg")),
+			"type": mediatype.JPEG,
+			"rel":  rel.Image,
+		})
+	}
+
+	formats := make([]string, 0, len(b.Formats))
+	for f := range b.Formats {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	for _, f := range formats {
+		pub.AddLink(map[string]any{
+			"href": lib.fileURL(b.Formats[f]),
+			"type": library.MediaType("." + f),
+			"rel":  rel.AcquisitionOpenAccess,
+		})
+	}
+}
+
+func (lib *Library) fileURL(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return lib.BaseURL + "/" + strings.Join(segments, "/")
+}
+
+// parseTime parse the timestamps stored by Calibre
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999-07:00",
+		"2006-01-02 15:04:05-07:00",
+		"2006-01-02T15:04:05.999999-07:00",
+		time.RFC3339,
+		"2006-01-02",
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
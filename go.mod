@@ -4,4 +4,8 @@ go 1.20
 
 require github.com/opds-community/libopds2-go v0.0.0-20170628075933-9c163cf60f6e
 
-require github.com/spf13/cast v1.5.1 // indirect
+require (
+	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/spf13/cast v1.5.1
+	golang.org/x/text v0.14.0
+)
@@ -0,0 +1,232 @@
+// Package html render OPDS feeds and publications as accessible html pages
+// for the humans browsing a catalog
+package html
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"strings"
+
+	"golang.org/x/text/language"
+
+	"github.com/ohzqq/libopds2-go/linkeddata"
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/opds2"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+//go:embed templates/*.html
+var defaultTemplates embed.FS
+
+// Renderer render feeds and publications with html/template, every
+// template can be overridden by name before the first render:
+//
+//	layout       page skeleton, call "content"
+//	feed         content of a feed page
+//	publication  content of a publication page
+//	card         publication in a list or a group
+//	navigation   list of navigation links
+//	facets       facet sidebar
+//	pagination   pagination controls
+//	search       search form
+type Renderer struct {
+	Templates *template.Template
+	// Lang is the lang attribute of the pages and the language titles and
+	// names are shown in
+	Lang string
+}
+
+// New create a renderer using the default templates
+func New() *Renderer {
+	t := template.Must(template.New("html").Funcs(Funcs()).ParseFS(defaultTemplates, "templates/*.html"))
+	return &Renderer{Templates: t, Lang: "en"}
+}
+
+// Override parse text in the renderer templates, the {{define}} it
+// contains replace the default templates with the same name
+func (r *Renderer) Override(text string) error {
+	_, err := r.Templates.Parse(text)
+	return err
+}
+
+// OverrideFS parse the templates matching patterns in fsys
+func (r *Renderer) OverrideFS(fsys fs.FS, patterns ...string) error {
+	_, err := r.Templates.ParseFS(fsys, patterns...)
+	return err
+}
+
+// page is the data given to the templates
+type page struct {
+	Lang        string
+	Title       string
+	Feed        *opds2.Feed
+	Publication *opds2.Publication
+	JSONLD      template.HTML
+}
+
+// RenderFeed write the html page of the feed, titles and names are shown in
+// the language of the renderer
+func (r *Renderer) RenderFeed(w io.Writer, feed *opds2.Feed) error {
+	feed = feed.Localize(r.languages()...)
+	return r.Templates.ExecuteTemplate(w, "layout", page{
+		Lang:  r.Lang,
+		Title: feed.Metadata.Title.String(),
+		Feed:  feed,
+	})
+}
+
+// RenderPublication write the html page of the publication with its
+// schema.org description embedded
+func (r *Renderer) RenderPublication(w io.Writer, pub *opds2.Publication) error {
+	script, err := linkeddata.ScriptTag(pub)
+	if err != nil {
+		return err
+	}
+	pub = pub.Localize(r.languages()...)
+	return r.Templates.ExecuteTemplate(w, "layout", page{
+		Lang:        r.Lang,
+		Title:       pub.Metadata.Title.String(),
+		Publication: pub,
+		JSONLD:      template.HTML(script),
+	})
+}
+
+// languages return the language of the renderer, none when it isn't a
+// valid BCP 47 tag
+func (r *Renderer) languages() []language.Tag {
+	t, err := language.Parse(r.Lang)
+	if err != nil {
+		return nil
+	}
+	return []language.Tag{t}
+}
+
+// Funcs return the functions available in the templates
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"thumbnail":    thumbnail,
+		"cover":        cover,
+		"rel":          relLink,
+		"acquisitions": acquisitions,
+		"detailLink":   detailLink,
+		"search":       search,
+		"isActive":     isActive,
+		"price":        price,
+		"formatName":   formatName,
+		"count":        count,
+	}
+}
+
+// thumbnail return the thumbnail of the publication, its cover otherwise
+func thumbnail(pub opds2.Publication) *opds2.Link {
+	if l := pub.FindFirstImageByRel(rel.Thumbnail); l.Href != "" {
+		return l
+	}
+	return cover(pub)
+}
+
+// cover return the cover of the publication, the first image otherwise
+func cover(pub opds2.Publication) *opds2.Link {
+	if l := pub.FindFirstImageByRel(rel.Image); l.Href != "" {
+		return l
+	}
+	if len(pub.Images) > 0 {
+		return pub.Images[0]
+	}
+	return &opds2.Link{}
+}
+
+// relLink return the first link with rel, an empty link otherwise
+func relLink(links opds2.Links, r string) *opds2.Link {
+	return links.FindFirstLinkByRel(r)
+}
+
+func acquisitions(pub opds2.Publication) opds2.Links {
+	return pub.Links.Acquisitions()
+}
+
+// detailLink return the link to the publication page, the first
+// acquisition link when the publication has no self link
+func detailLink(pub opds2.Publication) *opds2.Link {
+	if l := pub.FindFirstLinkByRel("self"); l.Href != "" {
+		return l
+	}
+	if acq := acquisitions(pub); len(acq) > 0 {
+		return acq[0]
+	}
+	return &opds2.Link{}
+}
+
+// searchForm is a form built from a templated search link
+type searchForm struct {
+	Action string
+	Param  string
+	Title  string
+}
+
+// search turn a templated search link like /search{?query} in a form,
+// nil when the feed has no usable search link
+func search(links opds2.Links) *searchForm {
+	for _, l := range links {
+		isSearch := false
+		for _, r := range l.Rel {
+			if r == "search" {
+				isSearch = true
+			}
+		}
+		if !isSearch || !l.Templated {
+			continue
+		}
+		i := strings.Index(l.Href, "{?")
+		j := strings.Index(l.Href, "}")
+		if i < 0 || j < i {
+			continue
+		}
+		params := strings.Split(l.Href[i+2:j], ",")
+		title := l.Title
+		if title == "" {
+			title = "Search"
+		}
+		return &searchForm{Action: l.Href[:i], Param: params[0], Title: title}
+	}
+	return nil
+}
+
+func isActive(l *opds2.Link) bool {
+	for _, r := range l.Rel {
+		if r == "self" {
+			return true
+		}
+	}
+	return false
+}
+
+// price return the prices of the link, separated by slashes
+func price(l *opds2.Link) string {
+	if l.Properties == nil {
+		return ""
+	}
+	var prices []string
+	for _, p := range l.Properties.Price {
+		prices = append(prices, p.String())
+	}
+	return strings.Join(prices, " / ")
+}
+
+// formatName return a short label for a media type
+func formatName(mt string) string {
+	if mt == "" {
+		return "Download"
+	}
+	return mediatype.Name(mt)
+}
+
+func count(l *opds2.Link) string {
+	if l.Properties == nil || l.Properties.NumberOfItems == 0 {
+		return ""
+	}
+	return fmt.Sprint(l.Properties.NumberOfItems)
+}
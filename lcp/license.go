@@ -0,0 +1,214 @@
+// Package lcp read Readium LCP License Documents and link them to the OPDS
+// acquisition links of protected publications
+// https://readium.org/lcp-specs/releases/lcp/latest
+package lcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// Scheme is the encryption scheme of LCP protected resources
+const Scheme = "http://readium.org/2014/01/lcp"
+
+// Encryption profiles
+const (
+	ProfileBasic = "http://readium.org/lcp/basic-profile"
+	Profile10    = "http://readium.org/lcp/profile-1.0"
+)
+
+// Algorithms of the content key, user key and signature
+const (
+	AlgorithmAES256CBC   = "http://www.w3.org/2001/04/xmlenc#aes256-cbc"
+	AlgorithmSHA256      = "http://www.w3.org/2001/04/xmlenc#sha256"
+	AlgorithmRSASHA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	AlgorithmECDSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+)
+
+// License is an LCP License Document
+type License struct {
+	ID         string     `json:"id"`
+	Issued     time.Time  `json:"issued"`
+	Updated    *time.Time `json:"updated,omitempty"`
+	Provider   string     `json:"provider"`
+	Encryption Encryption `json:"encryption"`
+	Links      []Link     `json:"links"`
+	User       *User      `json:"user,omitempty"`
+	Rights     *Rights    `json:"rights,omitempty"`
+	Signature  Signature  `json:"signature"`
+
+	// raw is the document as parsed, the signature is computed on it
+	raw []byte
+}
+
+// Encryption hold the keys of the license
+type Encryption struct {
+	Profile    string     `json:"profile"`
+	ContentKey ContentKey `json:"content_key"`
+	UserKey    UserKey    `json:"user_key"`
+}
+
+// ContentKey is the key of the publication encrypted with the user key
+type ContentKey struct {
+	Algorithm      string `json:"algorithm"`
+	EncryptedValue string `json:"encrypted_value"`
+}
+
+// UserKey describe how the user key is derived from the passphrase,
+// KeyCheck is the license id encrypted with the user key
+type UserKey struct {
+	Algorithm string `json:"algorithm"`
+	TextHint  string `json:"text_hint"`
+	KeyCheck  string `json:"key_check"`
+}
+
+// Link of a license, to the publication, the passphrase hint or the
+// license status
+type Link struct {
+	Rel       string `json:"rel"`
+	Href      string `json:"href"`
+	Type      string `json:"type,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	Templated bool   `json:"templated,omitempty"`
+	Length    int64  `json:"length,omitempty"`
+	Hash      string `json:"hash,omitempty"`
+}
+
+// User the license is issued to, Encrypted lists the fields encrypted
+// with the user key
+type User struct {
+	ID        string   `json:"id,omitempty"`
+	Email     string   `json:"email,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Encrypted []string `json:"encrypted,omitempty"`
+}
+
+// Rights granted by the license, nil values are unlimited
+type Rights struct {
+	Print *int       `json:"print,omitempty"`
+	Copy  *int       `json:"copy,omitempty"`
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// Signature of the license by the provider certificate
+type Signature struct {
+	Algorithm   string `json:"algorithm"`
+	Certificate string `json:"certificate"`
+	Value       string `json:"value"`
+}
+
+// Parse read a License Document
+func Parse(r io.Reader) (*License, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var lic License
+	if err := json.Unmarshal(data, &lic); err != nil {
+		return nil, err
+	}
+	lic.raw = data
+	return &lic, nil
+}
+
+// ParseFile read a License Document from a file
+func ParseFile(path string) (*License, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// ParseBuffer read a License Document from memory
+func ParseBuffer(data []byte) (*License, error) {
+	return Parse(bytes.NewReader(data))
+}
+
+// FindLink return the first link of the license with the relation r, an
+// empty link when there is none
+func (lic *License) FindLink(r string) *Link {
+	for i := range lic.Links {
+		if lic.Links[i].Rel == r {
+			return &lic.Links[i]
+		}
+	}
+	return &Link{}
+}
+
+// PublicationLink return the link to the protected publication
+func (lic *License) PublicationLink() *Link {
+	return lic.FindLink(rel.LCPPublication)
+}
+
+// StatusLink return the link to the License Status Document
+func (lic *License) StatusLink() *Link {
+	return lic.FindLink(rel.LCPStatus)
+}
+
+// HintLink return the link to the page helping the user to remember the
+// passphrase
+func (lic *License) HintLink() *Link {
+	return lic.FindLink(rel.LCPHint)
+}
+
+// Active check if the rights of the license allow to open the publication
+// at the time now
+func (lic *License) Active(now time.Time) bool {
+	if lic.Rights == nil {
+		return true
+	}
+	if lic.Rights.Start != nil && now.Before(*lic.Rights.Start) {
+		return false
+	}
+	if lic.Rights.End != nil && now.After(*lic.Rights.End) {
+		return false
+	}
+	return true
+}
+
+// Validate check the required fields of the license and the structure of
+// its signature, errors are joined
+func (lic *License) Validate() error {
+	var errs []error
+	if lic.ID == "" {
+		errs = append(errs, errors.New("lcp: missing id"))
+	}
+	if lic.Issued.IsZero() {
+		errs = append(errs, errors.New("lcp: missing issued date"))
+	}
+	if lic.Provider == "" {
+		errs = append(errs, errors.New("lcp: missing provider"))
+	}
+	if lic.Encryption.Profile == "" {
+		errs = append(errs, errors.New("lcp: missing encryption profile"))
+	}
+	if lic.Encryption.ContentKey.EncryptedValue == "" {
+		errs = append(errs, errors.New("lcp: missing content key"))
+	}
+	if lic.Encryption.UserKey.KeyCheck == "" {
+		errs = append(errs, errors.New("lcp: missing user key check"))
+	}
+	if lic.Encryption.UserKey.TextHint == "" {
+		errs = append(errs, errors.New("lcp: missing passphrase hint"))
+	}
+	if lic.PublicationLink().Href == "" {
+		errs = append(errs, errors.New("lcp: missing publication link"))
+	}
+	if lic.HintLink().Href == "" {
+		errs = append(errs, errors.New("lcp: missing hint link"))
+	}
+	if err := lic.Signature.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
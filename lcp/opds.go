@@ -0,0 +1,68 @@
+package lcp
+
+import (
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/opds2"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// IsLicenseLink check if the link gives an LCP license, directly or as the
+// first step of its indirect acquisition
+func IsLicenseLink(l *opds2.Link) bool {
+	if mediatype.Matches(mediatype.LCPLicense, l.TypeLink) {
+		return true
+	}
+	if l.Properties == nil {
+		return false
+	}
+	for _, ia := range l.Properties.IndirectAcquisition {
+		if mediatype.Matches(mediatype.LCPLicense, ia.TypeAcquisition) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsProtected check if the resource of the link is protected by LCP, from
+// its license or its encryption properties
+func IsProtected(l *opds2.Link) bool {
+	if IsLicenseLink(l) {
+		return true
+	}
+	if l.Properties != nil && l.Properties.Encrypted != nil {
+		return l.Properties.Encrypted.Scheme == Scheme
+	}
+	return false
+}
+
+// LicenseLinks return the acquisition links of the publication giving an
+// LCP license
+func LicenseLinks(pub *opds2.Publication) opds2.Links {
+	var links opds2.Links
+	for _, l := range pub.Links.Acquisitions() {
+		if IsLicenseLink(l) {
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// Acquisition return the acquisition link of the protected publication of
+// the license, with its encryption properties, to download it once the
+// license has been acquired
+func (lic *License) Acquisition() *opds2.Link {
+	pl := lic.PublicationLink()
+	return &opds2.Link{
+		Href:     pl.Href,
+		TypeLink: pl.Type,
+		Title:    pl.Title,
+		Rel:      []string{rel.Acquisition},
+		Properties: &opds2.Properties{
+			Encrypted: &opds2.Encrypted{
+				Scheme:    Scheme,
+				Profile:   lic.Encryption.Profile,
+				Algorithm: lic.Encryption.ContentKey.Algorithm,
+			},
+		},
+	}
+}
@@ -0,0 +1,151 @@
+package lcp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+)
+
+// ErrSignature is returned when the signature doesn't match the license
+var ErrSignature = errors.New("lcp: invalid signature")
+
+// ErrPassphrase is returned when the passphrase doesn't open the license
+var ErrPassphrase = errors.New("lcp: wrong passphrase")
+
+// validate check the algorithm of the signature and that its certificate
+// and value decode
+func (s Signature) validate() error {
+	switch s.Algorithm {
+	case AlgorithmRSASHA256, AlgorithmECDSASHA256:
+	case "":
+		return errors.New("lcp: missing signature algorithm")
+	default:
+		return errors.New("lcp: unsupported signature algorithm " + s.Algorithm)
+	}
+	if _, err := s.certificate(); err != nil {
+		return err
+	}
+	if v, err := base64.StdEncoding.DecodeString(s.Value); err != nil || len(v) == 0 {
+		return errors.New("lcp: invalid signature value")
+	}
+	return nil
+}
+
+func (s Signature) certificate() (*x509.Certificate, error) {
+	der, err := base64.StdEncoding.DecodeString(s.Certificate)
+	if err != nil || len(der) == 0 {
+		return nil, errors.New("lcp: invalid signature certificate")
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.New("lcp: invalid signature certificate: " + err.Error())
+	}
+	return cert, nil
+}
+
+// Verify check the signature of the license with the provider certificate
+// it embeds, the certificate itself is not checked against the LCP root
+func (lic *License) Verify() error {
+	if err := lic.Signature.validate(); err != nil {
+		return err
+	}
+	cert, _ := lic.Signature.certificate()
+	sig, _ := base64.StdEncoding.DecodeString(lic.Signature.Value)
+
+	data, err := lic.canonical()
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(data)
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) != nil {
+			return ErrSignature
+		}
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return ErrSignature
+		}
+	default:
+		return errors.New("lcp: unsupported certificate key")
+	}
+	return nil
+}
+
+// canonical return the signed form of the license: the document without
+// its signature, keys sorted and no whitespace
+func (lic *License) canonical() ([]byte, error) {
+	raw := lic.raw
+	if raw == nil {
+		var err error
+		if raw, err = json.Marshal(lic); err != nil {
+			return nil, err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	delete(doc, "signature")
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// CheckPassphrase check that the hex encoded SHA-256 hash of the user
+// passphrase, as given by the lcp_hashed_passphrase link property, opens
+// a license of the basic profile
+func (lic *License) CheckPassphrase(hashed string) error {
+	if lic.Encryption.Profile != ProfileBasic {
+		return errors.New("lcp: unsupported encryption profile " + lic.Encryption.Profile)
+	}
+	key, err := hex.DecodeString(hashed)
+	if err != nil || len(key) != sha256.Size {
+		return errors.New("lcp: invalid hashed passphrase")
+	}
+	check, err := base64.StdEncoding.DecodeString(lic.Encryption.UserKey.KeyCheck)
+	if err != nil {
+		return errors.New("lcp: invalid user key check")
+	}
+	id, err := decrypt(key, check)
+	if err != nil || string(id) != lic.ID {
+		return ErrPassphrase
+	}
+	return nil
+}
+
+// decrypt a value encrypted with AES-256-CBC, the IV first, with PKCS#7
+// padding
+func decrypt(key []byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("lcp: invalid encrypted value")
+	}
+	iv, data := data[:aes.BlockSize], append([]byte(nil), data[aes.BlockSize:]...)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, data)
+	pad := int(data[len(data)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return nil, errors.New("lcp: invalid padding")
+	}
+	return data[:len(data)-pad], nil
+}
@@ -0,0 +1,375 @@
+package library
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/opds2"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// group is a set of books sharing an author, a series, a subject or a
+// language
+type group struct {
+	Slug    string
+	Name    string
+	SortKey string
+	Books   []*Book
+}
+
+// Feeds return every feed of the catalog, including all pages, indexed by
+// their path relative to the base url
+func (lib *Library) Feeds() map[string]opds2.Feed {
+	feeds := make(map[string]opds2.Feed)
+
+	feeds["index"] = lib.Index()
+	for page := 1; page <= lib.pages(len(lib.Books)); page++ {
+		feeds[pagePath("all", page)] = lib.All(page)
+		feeds[pagePath("recent", page)] = lib.Recent(page)
+	}
+
+	for _, kind := range []string{"authors", "series", "subjects", "languages"} {
+		feeds[kind] = lib.Navigation(kind)
+		for _, g := range lib.groups(kind) {
+			for page := 1; page <= lib.pages(len(g.Books)); page++ {
+				feed, _ := lib.Group(kind, g.Slug, page)
+				feeds[pagePath(kind+"/"+g.Slug, page)] = feed
+			}
+		}
+	}
+
+	return feeds
+}
+
+// Index return the root navigation feed of the catalog
+func (lib *Library) Index() opds2.Feed {
+	feed := lib.newFeed("index", lib.Title, 1)
+
+	feed.AddNavigation("All books", lib.feedURL("all", 1), "subsection", mediatype.OPDS2)
+	feed.AddNavigation("Recently added", lib.feedURL("recent", 1), rel.SortNew, mediatype.OPDS2)
+	feed.AddNavigation("By author", lib.feedURL("authors", 1), "subsection", mediatype.OPDS2)
+	feed.AddNavigation("By series", lib.feedURL("series", 1), "subsection", mediatype.OPDS2)
+	feed.AddNavigation("By subject", lib.feedURL("subjects", 1), "subsection", mediatype.OPDS2)
+	feed.AddNavigation("By language", lib.feedURL("languages", 1), "subsection", mediatype.OPDS2)
+
+	return feed
+}
+
+// All return a page of every books sorted by title
+func (lib *Library) All(page int) opds2.Feed {
+	books := append([]*Book(nil), lib.Books...)
+	sortByTitle(books)
+	feed := lib.acquisitionFeed("all", "All books", books, page)
+	lib.addFacets(&feed, "all")
+	return feed
+}
+
+// Recent return a page of books, most recently added first
+func (lib *Library) Recent(page int) opds2.Feed {
+	books := append([]*Book(nil), lib.Books...)
+	sort.SliceStable(books, func(i, j int) bool {
+		return books[i].Added.After(books[j].Added)
+	})
+	feed := lib.acquisitionFeed("recent", "Recently added", books, page)
+	lib.addFacets(&feed, "recent")
+	return feed
+}
+
+// Navigation return the navigation feed listing the authors, series,
+// subjects or languages of the library
+func (lib *Library) Navigation(kind string) opds2.Feed {
+	feed := lib.newFeed(kind, browseTitle(kind), 1)
+	for _, g := range lib.groups(kind) {
+		l := &opds2.Link{
+			Href:       lib.feedURL(kind+"/"+g.Slug, 1),
+			TypeLink:   mediatype.OPDS2,
+			Rel:        []string{"subsection"},
+			Title:      g.Name,
+			Properties: &opds2.Properties{NumberOfItems: len(g.Books)},
+		}
+		feed.Navigation = append(feed.Navigation, l)
+	}
+	return feed
+}
+
+// Group return a page of the books of an author, series, subject or
+// language identified by its slug
+func (lib *Library) Group(kind string, slug string, page int) (opds2.Feed, bool) {
+	for _, g := range lib.groups(kind) {
+		if g.Slug == slug {
+			feed := lib.acquisitionFeed(kind+"/"+slug, g.Name, g.Books, page)
+			feed.AddLink(lib.feedURL(kind, 1), "up", mediatype.OPDS2, false)
+			if kind == "languages" {
+				lib.addFacets(&feed, kind+"/"+slug)
+			}
+			return feed, true
+		}
+	}
+	return opds2.Feed{}, false
+}
+
+func browseTitle(kind string) string {
+	switch kind {
+	case "authors":
+		return "Authors"
+	case "series":
+		return "Series"
+	case "subjects":
+		return "Subjects"
+	case "languages":
+		return "Languages"
+	}
+	return kind
+}
+
+// groups index the books by kind, books are sorted by series position for
+// series and by title otherwise. Groups are keyed by their name ignoring
+// case and spacing, the names giving the same slug, like "C" and "C++",
+// get a numeric suffix in the order of the groups.
+func (lib *Library) groups(kind string) []*group {
+	index := make(map[string]*group)
+	var groups []*group
+	add := func(name string, sortKey string, b *Book) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return
+		}
+		key := strings.ToLower(strings.Join(strings.Fields(name), " "))
+		g, ok := index[key]
+		if !ok {
+			g = &group{Name: name, SortKey: key}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		if sortKey != "" {
+			g.SortKey = strings.ToLower(sortKey)
+		}
+		for _, existing := range g.Books {
+			if existing == b {
+				return
+			}
+		}
+		g.Books = append(g.Books, b)
+	}
+
+	for _, b := range lib.Books {
+		m := b.Metadata
+		switch kind {
+		case "authors":
+			lang := ""
+			if len(m.Language) > 0 {
+				lang = m.Language[0]
+			}
+			for _, a := range m.Author {
+				add(a.Name.String(), a.SortKey(lang), b)
+			}
+		case "series":
+			if m.BelongsTo != nil {
+				for _, s := range m.BelongsTo.Series {
+					add(s.Name.String(), "", b)
+				}
+			}
+		case "subjects":
+			for _, s := range m.Subject {
+				add(s.Name, s.SortAs, b)
+			}
+		case "languages":
+			for _, l := range m.Language {
+				add(l, "", b)
+			}
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].SortKey < groups[j].SortKey
+	})
+	used := make(map[string]bool)
+	for _, g := range groups {
+		base := slugify(g.Name)
+		g.Slug = base
+		for n := 2; used[g.Slug]; n++ {
+			g.Slug = base + "-" + strconv.Itoa(n)
+		}
+		used[g.Slug] = true
+		if kind == "series" {
+			sortBySeries(g.Name, g.Books)
+		} else {
+			sortByTitle(g.Books)
+		}
+	}
+
+	return groups
+}
+
+func (lib *Library) newFeed(p string, title string, page int) opds2.Feed {
+	feed := opds2.New(title)
+	if !lib.Updated.IsZero() {
+		updated := lib.Updated
+		feed.Metadata.Modified = &updated
+	}
+	feed.AddLink(lib.feedURL(p, page), "self", mediatype.OPDS2, false)
+	feed.AddLink(lib.feedURL("index", 1), "start", mediatype.OPDS2, false)
+	return feed
+}
+
+// acquisitionFeed return the page of the books, links to the feed and its
+// pages are left out when p is empty
+func (lib *Library) acquisitionFeed(p string, title string, books []*Book, page int) opds2.Feed {
+	pages := lib.pages(len(books))
+	if page < 1 {
+		page = 1
+	}
+	if page > pages {
+		page = pages
+	}
+
+	var feed opds2.Feed
+	if p != "" {
+		feed = lib.newFeed(p, title, page)
+	} else {
+		feed = opds2.New(title)
+	}
+
+	start := (page - 1) * lib.perPage()
+	end := start + lib.perPage()
+	if end > len(books) {
+		end = len(books)
+	}
+	for _, b := range books[start:end] {
+		feed.Publications = append(feed.Publications, b.Publication)
+	}
+
+	var next, prev, first, last string
+	if pages > 1 && p != "" {
+		first = lib.feedURL(p, 1)
+		last = lib.feedURL(p, pages)
+		if page < pages {
+			next = lib.feedURL(p, page+1)
+		}
+		if page > 1 {
+			prev = lib.feedURL(p, page-1)
+		}
+	}
+	feed.AddPagination(len(books), lib.perPage(), page, next, prev, first, last)
+
+	return feed
+}
+
+// addFacets add the sort order and language facets, the facet matching
+// the current feed is marked as self
+func (lib *Library) addFacets(feed *opds2.Feed, current string) {
+	facet := func(p string, title string, count int, group string) {
+		l := &opds2.Link{
+			Href:       lib.feedURL(p, 1),
+			TypeLink:   mediatype.OPDS2,
+			Title:      title,
+			Properties: &opds2.Properties{NumberOfItems: count},
+		}
+		if p == current {
+			l.Rel = []string{"self"}
+		}
+		feed.AddFacet(l, group)
+	}
+
+	facet("all", "Title", len(lib.Books), "Sort")
+	facet("recent", "Recently added", len(lib.Books), "Sort")
+	for _, g := range lib.groups("languages") {
+		facet("languages/"+g.Slug, g.Name, len(g.Books), "Language")
+	}
+}
+
+func (lib *Library) perPage() int {
+	if lib.ItemsPerPage <= 0 {
+		return len(lib.Books) + 1
+	}
+	return lib.ItemsPerPage
+}
+
+func (lib *Library) pages(n int) int {
+	pages := (n + lib.perPage() - 1) / lib.perPage()
+	if pages < 1 {
+		return 1
+	}
+	return pages
+}
+
+func (lib *Library) feedURL(p string, page int) string {
+	if lib.FeedURL != nil {
+		return lib.FeedURL(p, page)
+	}
+	return lib.url(pagePath(p, page) + ".json")
+}
+
+// url escape each segment of the slash separated path p and prefix it with
+// the base url
+func (lib *Library) url(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return lib.BaseURL + "/" + strings.Join(segments, "/")
+}
+
+// pagePath return the path of a page, the first page use the bare path
+func pagePath(p string, page int) string {
+	if page <= 1 {
+		return p
+	}
+	return p + "_" + strconv.Itoa(page)
+}
+
+func sortByTitle(books []*Book) {
+	sort.SliceStable(books, func(i, j int) bool {
+		return titleKey(books[i]) < titleKey(books[j])
+	})
+}
+
+func sortBySeries(name string, books []*Book) {
+	position := func(b *Book) float64 {
+		if b.Metadata.BelongsTo != nil {
+			for _, s := range b.Metadata.BelongsTo.Series {
+				if s.Name.String() == name {
+					return s.Position
+				}
+			}
+		}
+		return 0
+	}
+	sort.SliceStable(books, func(i, j int) bool {
+		pi, pj := position(books[i]), position(books[j])
+		if pi != pj {
+			return opds2.LessSeriesPosition(pi, pj)
+		}
+		return titleKey(books[i]) < titleKey(books[j])
+	})
+}
+
+func titleKey(b *Book) string {
+	return strings.ToLower(b.Metadata.Title.String())
+}
+
+// slugify turn a name in a lower case path segment made of letters, digits
+// and dashes
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "unknown"
+	}
+	return slug
+}
@@ -0,0 +1,269 @@
+package library
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ohzqq/libopds2-go/opds2"
+)
+
+// EPUBImporter read the metadata of the OPF package of an EPUB file
+type EPUBImporter struct{}
+
+// Match EPUB files
+func (EPUBImporter) Match(p string) bool {
+	return strings.EqualFold(filepath.Ext(p), ".epub")
+}
+
+// Import open the EPUB container and parse its package document
+func (EPUBImporter) Import(p string) (*opds2.Publication, error) {
+	z, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := decodeZipXML(&z.Reader, "META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, errors.New("epub: no rootfile in container")
+	}
+
+	var pkg opfPackage
+	if err := decodeZipXML(&z.Reader, container.Rootfiles[0].FullPath, &pkg); err != nil {
+		return nil, err
+	}
+
+	return pkg.publication(), nil
+}
+
+func decodeZipXML(z *zip.Reader, name string, v any) error {
+	f, err := z.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return xml.NewDecoder(f).Decode(v)
+}
+
+type opfPackage struct {
+	UniqueIdentifier string `xml:"unique-identifier,attr"`
+	Metadata         struct {
+		Titles       []string        `xml:"title"`
+		Creators     []opfCreator    `xml:"creator"`
+		Contributors []opfCreator    `xml:"contributor"`
+		Identifiers  []opfIdentifier `xml:"identifier"`
+		Languages    []string        `xml:"language"`
+		Publishers   []string        `xml:"publisher"`
+		Subjects     []string        `xml:"subject"`
+		Description  string          `xml:"description"`
+		Dates        []string        `xml:"date"`
+		Rights       string          `xml:"rights"`
+		Metas        []opfMeta       `xml:"meta"`
+	} `xml:"metadata"`
+	Guide []struct {
+		Type string `xml:"type,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"guide>reference"`
+}
+
+type opfCreator struct {
+	ID     string `xml:"id,attr"`
+	Role   string `xml:"role,attr"`
+	FileAs string `xml:"file-as,attr"`
+	Name   string `xml:",chardata"`
+}
+
+type opfIdentifier struct {
+	ID     string `xml:"id,attr"`
+	Scheme string `xml:"scheme,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type opfMeta struct {
+	Name     string `xml:"name,attr"`
+	Content  string `xml:"content,attr"`
+	Property string `xml:"property,attr"`
+	Refines  string `xml:"refines,attr"`
+	ID       string `xml:"id,attr"`
+	Value    string `xml:",chardata"`
+}
+
+func parseOPF(r io.Reader) (*opfPackage, error) {
+	var pkg opfPackage
+	if err := xml.NewDecoder(r).Decode(&pkg); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// refines return the value of the EPUB 3 meta refining the element id
+func (pkg *opfPackage) refines(id string, property string) string {
+	if id == "" {
+		return ""
+	}
+	for _, m := range pkg.Metadata.Metas {
+		if m.Refines == "#"+id && m.Property == property {
+			return strings.TrimSpace(m.Value)
+		}
+	}
+	return ""
+}
+
+func (pkg *opfPackage) meta(name string) string {
+	for _, m := range pkg.Metadata.Metas {
+		if m.Name == name {
+			return strings.TrimSpace(m.Content)
+		}
+	}
+	return ""
+}
+
+func (pkg *opfPackage) identifier() string {
+	var id string
+	for _, i := range pkg.Metadata.Identifiers {
+		value := pkg.identifierURI(i)
+		if strings.HasPrefix(value, "urn:isbn:") && pkg.identifierScheme(i) == "isbn" {
+			return value
+		}
+		if id == "" || i.ID == pkg.UniqueIdentifier {
+			id = value
+		}
+	}
+	return id
+}
+
+// identifierURI return the identifier as an URN when its scheme is ISBN,
+// UUID or DOI
+func (pkg *opfPackage) identifierURI(i opfIdentifier) string {
+	value := strings.TrimSpace(i.Value)
+	if strings.HasPrefix(value, "urn:") {
+		return value
+	}
+	switch scheme := pkg.identifierScheme(i); scheme {
+	case "isbn":
+		return "urn:isbn:" + strings.ReplaceAll(value, "-", "")
+	case "uuid", "doi":
+		return "urn:" + scheme + ":" + value
+	}
+	return value
+}
+
+func (pkg *opfPackage) identifierScheme(i opfIdentifier) string {
+	if i.Scheme != "" {
+		return strings.ToLower(i.Scheme)
+	}
+	return strings.ToLower(pkg.refines(i.ID, "identifier-type"))
+}
+
+func (pkg *opfPackage) coverHref() string {
+	for _, r := range pkg.Guide {
+		if r.Type == "cover" {
+			return r.Href
+		}
+	}
+	return ""
+}
+
+func (pkg *opfPackage) publication() *opds2.Publication {
+	pub := &opds2.Publication{}
+	m := &pub.Metadata
+	md := pkg.Metadata
+
+	if len(md.Titles) > 0 {
+		m.Title.SingleString = strings.TrimSpace(md.Titles[0])
+	}
+	m.Identifier = pkg.identifier()
+	for _, i := range pkg.Metadata.Identifiers {
+		m.AddAltIdentifier(pkg.identifierURI(i))
+	}
+	m.Description = strings.TrimSpace(md.Description)
+	m.Rights = strings.TrimSpace(md.Rights)
+	for _, l := range md.Languages {
+		m.Language = append(m.Language, strings.TrimSpace(l))
+	}
+	for _, p := range md.Publishers {
+		m.Publisher = append(m.Publisher, opds2.NewContributor(strings.TrimSpace(p))...)
+	}
+	for _, s := range md.Subjects {
+		m.Subject = append(m.Subject, &opds2.Subject{Name: strings.TrimSpace(s)})
+	}
+	for _, d := range md.Dates {
+		if t, ok := parseOPFDate(d); ok {
+			m.PublicationDate = &t
+			break
+		}
+	}
+
+	for _, c := range md.Creators {
+		pkg.addContributor(m, c, "aut")
+	}
+	for _, c := range md.Contributors {
+		pkg.addContributor(m, c, "ctb")
+	}
+
+	if series := pkg.meta("calibre:series"); series != "" {
+		col := pub.BelongsToSeries(series)
+		col.Position, _ = strconv.ParseFloat(pkg.meta("calibre:series_index"), 64)
+	}
+	for _, meta := range md.Metas {
+		if meta.Property != "belongs-to-collection" || meta.Refines != "" {
+			continue
+		}
+		name := strings.TrimSpace(meta.Value)
+		pos, _ := strconv.ParseFloat(pkg.refines(meta.ID, "group-position"), 64)
+		var col *opds2.Collection
+		if pkg.refines(meta.ID, "collection-type") == "series" {
+			if pub.Metadata.BelongsTo != nil && len(pub.Metadata.BelongsTo.Series) > 0 {
+				continue
+			}
+			col = pub.BelongsToSeries(name)
+		} else {
+			col = pub.BelongsToCollection(name)
+		}
+		col.Position = pos
+	}
+
+	return pub
+}
+
+func (pkg *opfPackage) addContributor(m *opds2.PublicationMetadata, c opfCreator, role string) {
+	name := strings.TrimSpace(c.Name)
+	if name == "" {
+		return
+	}
+	if r := c.Role; r != "" {
+		role = r
+	} else if r := pkg.refines(c.ID, "role"); r != "" {
+		role = r
+	}
+	con := &opds2.Contributor{SortAs: c.FileAs}
+	con.Name.SingleString = name
+	if con.SortAs == "" {
+		con.SortAs = pkg.refines(c.ID, "file-as")
+	}
+
+	m.AddContributor(role, con)
+}
+
+func parseOPFDate(d string) (time.Time, bool) {
+	d = strings.TrimSpace(d)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, d); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
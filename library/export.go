@@ -0,0 +1,99 @@
+package library
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ohzqq/libopds2-go/html"
+	"github.com/ohzqq/libopds2-go/opds2"
+)
+
+// WriteOPDS2 write every feed of the catalog as static OPDS 2.0 json
+// files in dir, the base url of the library must point to dir
+func (lib *Library) WriteOPDS2(dir string) error {
+	for p, feed := range lib.Feeds() {
+		b, err := json.MarshalIndent(feed, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(p)+".json"), b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteOPDS1 write every feed of the catalog converted to OPDS 1.x as
+// static Atom files in dir, links to the other feeds use the .xml extension
+func (lib *Library) WriteOPDS1(dir string) error {
+	for p, feed := range lib.Feeds() {
+		f := feed.ToOPDS1(lib.feedURL(p, 1))
+		f.ID = lib.exportURL(f.ID, ".xml")
+		for i := range f.Links {
+			f.Links[i].Href = lib.exportURL(f.Links[i].Href, ".xml")
+		}
+		for i := range f.Entries {
+			for j := range f.Entries[i].Links {
+				f.Entries[i].Links[j].Href = lib.exportURL(f.Entries[i].Links[j].Href, ".xml")
+			}
+		}
+
+		var b strings.Builder
+		if err := f.Write(&b); err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(p)+".xml"), []byte(b.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteHTML write every feed of the catalog as static html pages in dir,
+// links to the other feeds use the .html extension
+func (lib *Library) WriteHTML(dir string, r *html.Renderer) error {
+	for p, feed := range lib.Feeds() {
+		feed := feed
+		rewrite := func(links opds2.Links) {
+			for _, l := range links {
+				l.Href = lib.exportURL(l.Href, ".html")
+			}
+		}
+		rewrite(feed.Links)
+		rewrite(feed.Navigation)
+		for _, f := range feed.Facets {
+			rewrite(f.Links)
+		}
+		for _, g := range feed.Groups {
+			rewrite(g.Links)
+			rewrite(g.Navigation)
+		}
+
+		var b strings.Builder
+		if err := r.RenderFeed(&b, &feed); err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(p)+".html"), []byte(b.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportURL change the extension of the links to the catalog feeds, the
+// feeds generated are only linking to other generated feeds and to files
+func (lib *Library) exportURL(href string, ext string) string {
+	if strings.HasPrefix(href, lib.BaseURL+"/") && strings.HasSuffix(href, ".json") {
+		return strings.TrimSuffix(href, ".json") + ext
+	}
+	return href
+}
+
+func writeFile(name string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, b, 0o644)
+}
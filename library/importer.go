@@ -0,0 +1,152 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/opds2"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// Importer build a publication from a file of the library
+type Importer interface {
+	Match(path string) bool
+	Import(path string) (*opds2.Publication, error)
+}
+
+// mediaTypes list the extensions of the files considered as books
+var mediaTypes = map[string]string{
+	".epub": mediatype.EPUB,
+	".pdf":  mediatype.PDF,
+	".mobi": mediatype.MOBI,
+	".azw3": mediatype.AZW3,
+	".cbz":  mediatype.CBZ,
+	".cbr":  mediatype.CBR,
+	".fb2":  mediatype.FB2,
+	".djvu": mediatype.DjVu,
+	".txt":  mediatype.Text,
+	".m4b":  mediatype.MP4Audio,
+	".mp3":  mediatype.MP3,
+	".lpf":  mediatype.LCPAudiobook,
+}
+
+// MediaType return the media type of a book from its extension
+func MediaType(path string) string {
+	if t, ok := mediaTypes[strings.ToLower(filepath.Ext(path))]; ok {
+		return t
+	}
+	return mediatype.OctetStream
+}
+
+// IsBook check if the file extension is a known book format
+func IsBook(path string) bool {
+	_, ok := mediaTypes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// DefaultImporters return the importers used by a new library: Calibre
+// like metadata.opf sidecar, EPUB package metadata and finally the file name
+func DefaultImporters() []Importer {
+	return []Importer{
+		SidecarImporter{},
+		EPUBImporter{},
+		FileImporter{},
+	}
+}
+
+// SidecarImporter read the metadata.opf file stored next to the book,
+// as done by Calibre
+type SidecarImporter struct{}
+
+// Match check for a metadata.opf in the directory of the book
+func (SidecarImporter) Match(path string) bool {
+	if !IsBook(path) {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(path), "metadata.opf"))
+	return err == nil
+}
+
+// Import parse the sidecar metadata.opf
+func (SidecarImporter) Import(path string) (*opds2.Publication, error) {
+	opfPath := filepath.Join(filepath.Dir(path), "metadata.opf")
+	f, err := os.Open(opfPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pkg, err := parseOPF(f)
+	if err != nil {
+		return nil, err
+	}
+	pub := pkg.publication()
+
+	cover := pkg.coverHref()
+	if cover == "" {
+		cover = "cover.jpg"
+	}
+	pub.AddImage(map[string]any{
+		"href": filepath.Join(filepath.Dir(path), filepath.FromSlash(cover)),
+		"type": imageType(cover),
+		"rel":  rel.Image,
+	})
+
+	return pub, nil
+}
+
+// FileImporter use the file name as title, names like "Author - Title.epub"
+// also set the author
+type FileImporter struct{}
+
+// Match any known book format
+func (FileImporter) Match(path string) bool {
+	return IsBook(path)
+}
+
+// Import build the publication from the file name
+func (FileImporter) Import(path string) (*opds2.Publication, error) {
+	pub := &opds2.Publication{}
+	title := titleFromFilename(path)
+	if author, t, ok := strings.Cut(title, " - "); ok {
+		pub.Metadata.Author = opds2.NewContributor(strings.TrimSpace(author))
+		title = t
+	}
+	pub.Metadata.Title.SingleString = strings.TrimSpace(title)
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range []string{".jpg", ".jpeg", ".png"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			pub.AddImage(map[string]any{
+				"href": base + ext,
+				"type": imageType(ext),
+				"rel":  rel.Image,
+			})
+			break
+		}
+	}
+
+	return pub, nil
+}
+
+func titleFromFilename(path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.ReplaceAll(name, "_", " ")
+}
+
+func imageType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return mediatype.PNG
+	case ".gif":
+		return mediatype.GIF
+	case ".svg":
+		return mediatype.SVG
+	case ".webp":
+		return mediatype.WebP
+	}
+	return mediatype.JPEG
+}
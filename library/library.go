@@ -0,0 +1,194 @@
+// Package library build a browsable OPDS catalog from a directory of books
+package library
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ohzqq/libopds2-go/opds2"
+	"github.com/ohzqq/libopds2-go/rel"
+	"github.com/ohzqq/libopds2-go/search"
+)
+
+// Book is a publication found in the library with the files it was
+// imported from
+type Book struct {
+	opds2.Publication
+	Files []string
+	Added time.Time
+}
+
+// Library scan a directory tree and generate OPDS feeds for the books found
+type Library struct {
+	Dir          string
+	BaseURL      string
+	Title        string
+	ItemsPerPage int
+	Importers    []Importer
+	Books        []*Book
+	Updated      time.Time
+	// FeedURL return the url of a page of the feed at path, the static
+	// layout used by WriteOPDS2 is used when nil
+	FeedURL func(path string, page int) string
+	// SearchIndex rank the results of Search when set, see BuildIndex
+	SearchIndex *search.Index
+}
+
+// New create a library for the directory dir, books are served from
+// baseURL which is also used as prefix of every generated feed
+func New(dir string, baseURL string) *Library {
+	return &Library{
+		Dir:          dir,
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		Title:        filepath.Base(dir),
+		ItemsPerPage: 50,
+		Importers:    DefaultImporters(),
+	}
+}
+
+// Scan walk the library directory and import every file matched by one of
+// the importers, files sharing an identifier are merged in the same book,
+// a file is given to the next matching importer when one fails and the
+// files no importer could read are returned as errors after the scan
+func (lib *Library) Scan() error {
+	lib.Books = nil
+	byID := make(map[string]*Book)
+	var errs []error
+
+	err := filepath.WalkDir(lib.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != lib.Dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		pub, err := lib.importFile(p)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if pub == nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(lib.Dir, p)
+		if err != nil {
+			return err
+		}
+
+		if pub.Metadata.Identifier == "" {
+			pub.Metadata.Identifier = fileIdentifier(relPath)
+		}
+		if pub.Metadata.Title.String() == "" {
+			pub.Metadata.Title.SingleString = titleFromFilename(p)
+		}
+
+		book, ok := byID[pub.Metadata.Identifier]
+		if !ok {
+			book = &Book{Publication: *pub}
+			book.Publication.Links = nil
+			book.Publication.Images = nil
+			for _, img := range pub.Images {
+				lib.addImage(book, img)
+			}
+			byID[pub.Metadata.Identifier] = book
+			lib.Books = append(lib.Books, book)
+		}
+		if info.ModTime().After(book.Added) {
+			book.Added = info.ModTime()
+		}
+		lib.touch(book)
+		book.Files = append(book.Files, relPath)
+		book.AddLink(map[string]any{
+			"href": lib.fileURL(relPath),
+			"type": MediaType(p),
+			"rel":  rel.AcquisitionOpenAccess,
+		})
+		return nil
+	})
+
+	for _, book := range lib.Books {
+		if book.Metadata.Modified == nil {
+			added := book.Added
+			book.Metadata.Modified = &added
+		}
+	}
+
+	return errors.Join(append([]error{err}, errs...)...)
+}
+
+// Add add books imported from another source than the directory scan
+func (lib *Library) Add(books ...*Book) {
+	for _, book := range books {
+		lib.Books = append(lib.Books, book)
+		lib.touch(book)
+	}
+}
+
+func (lib *Library) touch(book *Book) {
+	if book.Added.After(lib.Updated) {
+		lib.Updated = book.Added
+	}
+}
+
+// importFile import p with the first matching importer that succeeds, a
+// nil publication when no importer match
+func (lib *Library) importFile(p string) (*opds2.Publication, error) {
+	var err error
+	for _, i := range lib.Importers {
+		if !i.Match(p) {
+			continue
+		}
+		var pub *opds2.Publication
+		if pub, err = i.Import(p); err == nil {
+			return pub, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("library: %s: %w", p, err)
+	}
+	return nil, nil
+}
+
+// addImage add an image found by an importer, local files are turned in
+// an url relative to the library base url, the files missing or outside
+// of the library are dropped
+func (lib *Library) addImage(book *Book, img *opds2.Link) {
+	if u, err := url.Parse(img.Href); err != nil || len(u.Scheme) <= 1 {
+		relPath, err := filepath.Rel(lib.Dir, img.Href)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			return
+		}
+		if _, err := os.Stat(img.Href); err != nil {
+			return
+		}
+		img.Href = lib.fileURL(relPath)
+	}
+	book.Images = append(book.Images, img)
+}
+
+func (lib *Library) fileURL(relPath string) string {
+	return lib.url(path.Clean(filepath.ToSlash(relPath)))
+}
+
+func fileIdentifier(relPath string) string {
+	relPath = strings.TrimSuffix(filepath.ToSlash(relPath), filepath.Ext(relPath))
+	sum := sha1.Sum([]byte(relPath))
+	return "urn:sha1:" + hex.EncodeToString(sum[:])
+}
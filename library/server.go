@@ -0,0 +1,156 @@
+package library
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ohzqq/libopds2-go/opds2"
+	"github.com/ohzqq/libopds2-go/opdsserver"
+	"github.com/ohzqq/libopds2-go/search"
+)
+
+// Handler return a server for the library mounted at prefix, the links of
+// the generated feeds follow the routes of the server, the FeedURL of the
+// library is left unchanged
+func (lib *Library) Handler(prefix string) *opdsserver.Server {
+	c := &serverCatalog{lib: lib}
+	s := opdsserver.New(c, prefix)
+	s.Title = lib.Title
+	c.feedURL = func(p string, page int) string {
+		segments := strings.Split(p, "/")
+		for i, seg := range segments {
+			segments[i] = url.PathEscape(seg)
+		}
+		u := s.BrowseURL(strings.Join(segments, "/"))
+		if p == "index" {
+			u = s.URL("")
+		}
+		if page > 1 {
+			u += "?page=" + strconv.Itoa(page)
+		}
+		return u
+	}
+	return s
+}
+
+// serverCatalog is the catalog served by Handler, the feeds are generated
+// by a copy of the library whose FeedURL follow the routes of the server
+type serverCatalog struct {
+	lib     *Library
+	feedURL func(path string, page int) string
+}
+
+// library return a copy of the library using the urls of the server, made
+// for each request so the books scanned since are served
+func (c *serverCatalog) library() *Library {
+	lib := *c.lib
+	lib.FeedURL = c.feedURL
+	return &lib
+}
+
+func (c *serverCatalog) Root(ctx context.Context) (*opds2.Feed, error) {
+	return c.library().Root(ctx)
+}
+
+func (c *serverCatalog) Browse(ctx context.Context, p string, page int) (*opds2.Feed, error) {
+	return c.library().Browse(ctx, p, page)
+}
+
+func (c *serverCatalog) Search(ctx context.Context, query string, page int) (*opds2.Feed, error) {
+	return c.library().Search(ctx, query, page)
+}
+
+func (c *serverCatalog) Publication(ctx context.Context, id string) (*opds2.Publication, error) {
+	return c.library().Publication(ctx, id)
+}
+
+// Root implement opdsserver.Catalog
+func (lib *Library) Root(ctx context.Context) (*opds2.Feed, error) {
+	feed := lib.Index()
+	return &feed, nil
+}
+
+// Browse implement opdsserver.Catalog, path is one of the paths returned
+// by Feeds without the page suffix
+func (lib *Library) Browse(ctx context.Context, p string, page int) (*opds2.Feed, error) {
+	var feed opds2.Feed
+	switch p {
+	case "index":
+		feed = lib.Index()
+	case "all":
+		feed = lib.All(page)
+	case "recent":
+		feed = lib.Recent(page)
+	case "authors", "series", "subjects", "languages":
+		feed = lib.Navigation(p)
+	default:
+		kind, slug, _ := strings.Cut(p, "/")
+		f, ok := lib.Group(kind, slug, page)
+		if !ok {
+			return nil, opdsserver.ErrNotFound
+		}
+		feed = f
+	}
+	return &feed, nil
+}
+
+// Search implement opdsserver.Catalog, books whose title, authors, series
+// or subjects contain every word of the query are returned, ranked by the
+// search index when the library has one
+func (lib *Library) Search(ctx context.Context, query string, page int) (*opds2.Feed, error) {
+	if lib.SearchIndex != nil {
+		feed := lib.SearchIndex.Feed(query, page, lib.perPage())
+		return &feed, nil
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	var books []*Book
+	for _, b := range lib.Books {
+		text := strings.ToLower(searchText(b))
+		match := len(words) > 0
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				match = false
+				break
+			}
+		}
+		if match {
+			books = append(books, b)
+		}
+	}
+	sortByTitle(books)
+	feed := lib.acquisitionFeed("", "Search: "+query, books, page)
+	return &feed, nil
+}
+
+// BuildIndex index the books of the library for Search
+func (lib *Library) BuildIndex() *search.Index {
+	ix := search.New()
+	for _, b := range lib.Books {
+		ix.Add(b.Publication)
+	}
+	lib.SearchIndex = ix
+	return ix
+}
+
+// Publication implement opdsserver.Catalog
+func (lib *Library) Publication(ctx context.Context, id string) (*opds2.Publication, error) {
+	for _, b := range lib.Books {
+		if b.Metadata.Identifier == id {
+			pub := b.Publication
+			return &pub, nil
+		}
+	}
+	return nil, opdsserver.ErrNotFound
+}
+
+func searchText(b *Book) string {
+	m := b.Metadata
+	parts := []string{m.Title.String(), m.Author.String(), m.Subject.String()}
+	if m.BelongsTo != nil {
+		parts = append(parts, m.BelongsTo.Series.String())
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,109 @@
+package linkeddata
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/ohzqq/libopds2-go/opds2"
+)
+
+// Namespaces of the RDF/XML documents
+const (
+	NamespaceRDF     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	NamespaceDCTerms = "http://purl.org/dc/terms/"
+)
+
+// RDF is the root of a Dublin Core RDF/XML document
+type RDF struct {
+	XMLName      xml.Name      `xml:"rdf:RDF"`
+	XMLNSRDF     string        `xml:"xmlns:rdf,attr"`
+	XMLNSDC      string        `xml:"xmlns:dcterms,attr"`
+	Descriptions []Description `xml:"rdf:Description"`
+}
+
+// Description is the Dublin Core description of a publication
+type Description struct {
+	About       string   `xml:"rdf:about,attr,omitempty"`
+	Title       string   `xml:"dcterms:title"`
+	Identifier  string   `xml:"dcterms:identifier,omitempty"`
+	Creator     []string `xml:"dcterms:creator,omitempty"`
+	Contributor []string `xml:"dcterms:contributor,omitempty"`
+	Publisher   []string `xml:"dcterms:publisher,omitempty"`
+	Language    []string `xml:"dcterms:language,omitempty"`
+	Issued      string   `xml:"dcterms:issued,omitempty"`
+	Modified    string   `xml:"dcterms:modified,omitempty"`
+	Description string   `xml:"dcterms:description,omitempty"`
+	Subject     []string `xml:"dcterms:subject,omitempty"`
+	IsPartOf    []string `xml:"dcterms:isPartOf,omitempty"`
+	Rights      string   `xml:"dcterms:rights,omitempty"`
+	Source      string   `xml:"dcterms:source,omitempty"`
+	Format      []string `xml:"dcterms:format,omitempty"`
+	Extent      string   `xml:"dcterms:extent,omitempty"`
+}
+
+// DublinCore convert the publication to a Dublin Core description, the
+// authors are creators and every other role a contributor
+func DublinCore(pub *opds2.Publication) Description {
+	m := &pub.Metadata
+	d := Description{
+		Title:       m.Title.String(),
+		Identifier:  m.Identifier,
+		Creator:     m.Author.StringSlice(),
+		Publisher:   m.Publisher.StringSlice(),
+		Language:    m.Language,
+		Description: m.Description,
+		Subject:     m.Subject.StringSlice(),
+		Rights:      m.Rights,
+		Source:      m.Source,
+	}
+	if strings.Contains(m.Identifier, ":") {
+		d.About = m.Identifier
+	}
+	for _, cons := range []opds2.Contributors{m.Translator, m.Editor, m.Artist, m.Illustrator, m.Letterer,
+		m.Penciler, m.Colorist, m.Inker, m.Narrator, m.Contributor} {
+		d.Contributor = append(d.Contributor, cons.StringSlice()...)
+	}
+	if m.PublicationDate != nil {
+		d.Issued = m.PublicationDate.Format("2006-01-02")
+	}
+	if m.Modified != nil {
+		d.Modified = m.Modified.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if m.BelongsTo != nil {
+		d.IsPartOf = append(m.BelongsTo.Series.StringSlice(), m.BelongsTo.Collection.StringSlice()...)
+	}
+	if m.Duration > 0 {
+		d.Extent = isoDuration(m.Duration)
+	}
+
+	seen := make(map[string]bool)
+	for _, l := range pub.Links {
+		if l.TypeLink == "" || seen[l.TypeLink] || !l.IsAcquisition() {
+			continue
+		}
+		seen[l.TypeLink] = true
+		d.Format = append(d.Format, l.TypeLink)
+	}
+
+	return d
+}
+
+// WriteDublinCore write the publications as an RDF/XML document
+func WriteDublinCore(w io.Writer, pubs ...*opds2.Publication) error {
+	doc := RDF{XMLNSRDF: NamespaceRDF, XMLNSDC: NamespaceDCTerms}
+	for _, p := range pubs {
+		doc.Descriptions = append(doc.Descriptions, DublinCore(p))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
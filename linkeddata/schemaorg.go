@@ -0,0 +1,200 @@
+// Package linkeddata export publications as schema.org JSON-LD and as
+// Dublin Core RDF/XML
+package linkeddata
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/ohzqq/libopds2-go/opds2"
+)
+
+// Book is a schema.org Book or Audiobook
+type Book struct {
+	Context       string   `json:"@context,omitempty"`
+	Type          string   `json:"@type"`
+	ID            string   `json:"@id,omitempty"`
+	Name          string   `json:"name"`
+	URL           string   `json:"url,omitempty"`
+	Identifier    string   `json:"identifier,omitempty"`
+	ISBN          string   `json:"isbn,omitempty"`
+	Author        []Person `json:"author,omitempty"`
+	Translator    []Person `json:"translator,omitempty"`
+	Illustrator   []Person `json:"illustrator,omitempty"`
+	Editor        []Person `json:"editor,omitempty"`
+	ReadBy        []Person `json:"readBy,omitempty"`
+	Contributor   []Person `json:"contributor,omitempty"`
+	Publisher     []Person `json:"publisher,omitempty"`
+	InLanguage    []string `json:"inLanguage,omitempty"`
+	DatePublished string   `json:"datePublished,omitempty"`
+	DateModified  string   `json:"dateModified,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	About         []Thing  `json:"about,omitempty"`
+	IsPartOf      []Series `json:"isPartOf,omitempty"`
+	Position      string   `json:"position,omitempty"`
+	Image         []string `json:"image,omitempty"`
+	Offers        []Offer  `json:"offers,omitempty"`
+	Duration      string   `json:"duration,omitempty"`
+	License       string   `json:"license,omitempty"`
+}
+
+// Person is a schema.org Person or Organization
+type Person struct {
+	Type   string   `json:"@type"`
+	Name   string   `json:"name"`
+	SameAs []string `json:"sameAs,omitempty"`
+}
+
+// Thing is a subject of the book
+type Thing struct {
+	Type       string `json:"@type"`
+	Name       string `json:"name"`
+	Identifier string `json:"identifier,omitempty"`
+	InScheme   string `json:"inDefinedTermSet,omitempty"`
+}
+
+// Series is the series or collection the book is part of
+type Series struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// Offer is the price of an acquisition link
+type Offer struct {
+	Type          string        `json:"@type"`
+	Price         opds2.Decimal `json:"price"`
+	PriceCurrency string        `json:"priceCurrency"`
+	URL           string        `json:"url,omitempty"`
+}
+
+// SchemaOrg convert the publication to a schema.org Book, an Audiobook when
+// the publication type says so
+func SchemaOrg(pub *opds2.Publication) *Book {
+	m := &pub.Metadata
+	b := &Book{
+		Context:     "https://schema.org",
+		Type:        "Book",
+		Name:        m.Title.String(),
+		Identifier:  m.Identifier,
+		Description: m.Description,
+		InLanguage:  m.Language,
+		License:     m.Rights,
+	}
+	if strings.HasSuffix(m.RDFType, "Audiobook") {
+		b.Type = "Audiobook"
+	}
+	if strings.HasPrefix(m.Identifier, "urn:isbn:") {
+		b.ISBN = strings.TrimPrefix(m.Identifier, "urn:isbn:")
+	}
+	if strings.Contains(m.Identifier, ":") {
+		b.ID = m.Identifier
+	}
+	if self := pub.FindFirstLinkByRel("self"); self.Href != "" {
+		b.URL = self.Href
+	}
+
+	b.Author = persons(m.Author, "Person")
+	b.Translator = persons(m.Translator, "Person")
+	b.Illustrator = persons(m.Illustrator, "Person")
+	b.Editor = persons(m.Editor, "Person")
+	b.ReadBy = persons(m.Narrator, "Person")
+	for _, cons := range []opds2.Contributors{m.Artist, m.Letterer, m.Penciler, m.Colorist, m.Inker, m.Contributor} {
+		b.Contributor = append(b.Contributor, persons(cons, "Person")...)
+	}
+	b.Publisher = persons(m.Publisher, "Organization")
+
+	if m.PublicationDate != nil {
+		b.DatePublished = m.PublicationDate.Format("2006-01-02")
+	}
+	if m.Modified != nil {
+		b.DateModified = m.Modified.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if m.Duration > 0 {
+		b.Duration = isoDuration(m.Duration)
+	}
+
+	for _, s := range m.Subject {
+		b.About = append(b.About, Thing{Type: "DefinedTerm", Name: s.Name, Identifier: s.Code, InScheme: s.Scheme})
+	}
+
+	if m.BelongsTo != nil {
+		for _, s := range m.BelongsTo.Series {
+			b.IsPartOf = append(b.IsPartOf, collectionSeries(s, "BookSeries"))
+			if b.Position == "" && s.Position != 0 {
+				b.Position = strconv.FormatFloat(s.Position, 'f', -1, 64)
+			}
+		}
+		for _, c := range m.BelongsTo.Collection {
+			b.IsPartOf = append(b.IsPartOf, collectionSeries(c, "Collection"))
+		}
+	}
+
+	for _, img := range pub.Images {
+		b.Image = append(b.Image, img.Href)
+	}
+
+	for _, l := range pub.Links {
+		if l.Properties == nil {
+			continue
+		}
+		for _, p := range l.Properties.Price {
+			b.Offers = append(b.Offers, Offer{
+				Type:          "Offer",
+				Price:         p.Value,
+				PriceCurrency: p.Currency,
+				URL:           l.Href,
+			})
+		}
+	}
+
+	return b
+}
+
+// JSONLD return the schema.org JSON-LD document of the publication
+func JSONLD(pub *opds2.Publication) ([]byte, error) {
+	return json.MarshalIndent(SchemaOrg(pub), "", "  ")
+}
+
+// ScriptTag return the JSON-LD of the publication in a script element to
+// embed in an html page, the json encoding escape < and > so the content
+// can't close the element
+func ScriptTag(pub *opds2.Publication) (string, error) {
+	b, err := json.Marshal(SchemaOrg(pub))
+	if err != nil {
+		return "", err
+	}
+	return `<script type="application/ld+json">` + string(b) + `</script>`, nil
+}
+
+func persons(cons opds2.Contributors, typ string) []Person {
+	var ps []Person
+	for _, c := range cons {
+		p := Person{Type: typ, Name: c.Name.String()}
+		if strings.HasPrefix(c.Identifier, "http") {
+			p.SameAs = append(p.SameAs, c.Identifier)
+		}
+		for _, l := range c.Links {
+			p.SameAs = append(p.SameAs, l.Href)
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+func collectionSeries(c *opds2.Collection, typ string) Series {
+	s := Series{Type: typ}
+	if c.Contributor != nil {
+		s.Name = c.Name.String()
+		if len(c.Links) > 0 {
+			s.URL = c.Links[0].Href
+		}
+	}
+	return s
+}
+
+// isoDuration format a duration in seconds as an ISO 8601 duration
+func isoDuration(seconds int) string {
+	return "PT" + strconv.Itoa(seconds) + "S"
+}
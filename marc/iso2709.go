@@ -0,0 +1,217 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ISO 2709 delimiters
+const (
+	subfieldDelimiter = 0x1f
+	fieldTerminator   = 0x1e
+	recordTerminator  = 0x1d
+)
+
+const leaderLength = 24
+
+// ErrInvalidRecord is returned when a binary record is malformed
+var ErrInvalidRecord = errors.New("marc: invalid ISO 2709 record")
+
+// Reader read ISO 2709 records from a stream
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader create a reader of ISO 2709 records
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read return the next record, io.EOF at the end of the stream
+func (r *Reader) Read() (*Record, error) {
+	data, err := r.r.ReadBytes(recordTerminator)
+	if err == io.EOF {
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil, io.EOF
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	rec := &Record{}
+	if err := rec.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// ReadAll return every record of the stream
+func (r *Reader) ReadAll() ([]*Record, error) {
+	var records []*Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// Writer write ISO 2709 records to a stream
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter create a writer of ISO 2709 records
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write encode the record
+func (w *Writer) Write(rec *Record) error {
+	b, err := rec.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(b)
+	return err
+}
+
+// UnmarshalBinary decode an ISO 2709 record
+func (r *Record) UnmarshalBinary(data []byte) error {
+	if len(data) < leaderLength+1 {
+		return ErrInvalidRecord
+	}
+	r.Leader = string(data[:leaderLength])
+	base, ok := parseDigits(data[12:17])
+	if !ok || base <= leaderLength || base > len(data) {
+		return ErrInvalidRecord
+	}
+
+	directory := data[leaderLength : base-1]
+	if len(directory)%12 != 0 {
+		return ErrInvalidRecord
+	}
+	r.ControlFields = nil
+	r.DataFields = nil
+
+	for i := 0; i < len(directory); i += 12 {
+		entry := directory[i : i+12]
+		tag := string(entry[:3])
+		length, okL := parseDigits(entry[3:7])
+		start, okS := parseDigits(entry[7:12])
+		if !okL || !okS || base+start+length > len(data) || length < 1 {
+			return ErrInvalidRecord
+		}
+		field := data[base+start : base+start+length-1]
+
+		if tag < "010" {
+			r.AddControlField(tag, string(field))
+			continue
+		}
+
+		if len(field) < 2 {
+			return ErrInvalidRecord
+		}
+		df := DataField{Tag: tag, Ind1: string(field[0]), Ind2: string(field[1])}
+		for _, sf := range bytes.Split(field[2:], []byte{subfieldDelimiter}) {
+			if len(sf) == 0 {
+				continue
+			}
+			df.Subfields = append(df.Subfields, Subfield{Code: string(sf[0]), Value: string(sf[1:])})
+		}
+		r.DataFields = append(r.DataFields, df)
+	}
+
+	return nil
+}
+
+// parseDigits read the unsigned decimal numbers of the leader and the
+// directory, strconv.Atoi would accept a sign
+func parseDigits(b []byte) (int, bool) {
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, len(b) > 0
+}
+
+// MarshalBinary encode the record in ISO 2709, the lengths and base
+// address of the leader are computed, character coding is set to UTF-8
+func (r *Record) MarshalBinary() ([]byte, error) {
+	var directory, fields bytes.Buffer
+
+	addEntry := func(tag string, data []byte) error {
+		if len(tag) != 3 {
+			return fmt.Errorf("marc: invalid tag %q", tag)
+		}
+		if len(data) > 9999 {
+			return fmt.Errorf("marc: field %s too long", tag)
+		}
+		fmt.Fprintf(&directory, "%s%04d%05d", tag, len(data), fields.Len())
+		fields.Write(data)
+		return nil
+	}
+
+	for _, f := range r.ControlFields {
+		if err := addEntry(f.Tag, append([]byte(f.Value), fieldTerminator)); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range r.DataFields {
+		var b bytes.Buffer
+		b.WriteString(indicator(f.Ind1))
+		b.WriteString(indicator(f.Ind2))
+		for _, sf := range f.Subfields {
+			b.WriteByte(subfieldDelimiter)
+			b.WriteString(sf.Code)
+			b.WriteString(sf.Value)
+		}
+		b.WriteByte(fieldTerminator)
+		if err := addEntry(f.Tag, b.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	directory.WriteByte(fieldTerminator)
+
+	base := leaderLength + directory.Len()
+	length := base + fields.Len() + 1
+	if length > 99999 {
+		return nil, errors.New("marc: record too long")
+	}
+
+	leader := []byte(r.Leader)
+	if len(leader) != leaderLength {
+		leader = []byte(defaultLeader)
+	}
+	copy(leader[0:5], fmt.Sprintf("%05d", length))
+	leader[9] = 'a'
+	copy(leader[10:12], "22")
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+	copy(leader[20:24], "4500")
+
+	var out bytes.Buffer
+	out.Grow(length)
+	out.Write(leader)
+	out.Write(directory.Bytes())
+	out.Write(fields.Bytes())
+	out.WriteByte(recordTerminator)
+	return out.Bytes(), nil
+}
+
+func indicator(ind string) string {
+	if ind == "" {
+		return " "
+	}
+	return ind[:1]
+}
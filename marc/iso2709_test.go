@@ -0,0 +1,60 @@
+package marc
+
+import (
+	"errors"
+	"testing"
+)
+
+func testRecord(t *testing.T) []byte {
+	t.Helper()
+	rec := &Record{}
+	rec.AddControlField("001", "ocm12345")
+	rec.AddField("245", "1", "0", "a", "Moby Dick", "c", "Herman Melville")
+	data, err := rec.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	var rec Record
+	if err := rec.UnmarshalBinary(testRecord(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.ControlField("001"); got != "ocm12345" {
+		t.Errorf("001 = %q", got)
+	}
+	if fields := rec.Fields("245"); len(fields) != 1 || fields[0].Subfield("a") != "Moby Dick" {
+		t.Errorf("245 = %v", fields)
+	}
+}
+
+func TestUnmarshalMalformedRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int
+		value  string
+	}{
+		{"negative start", leaderLength + 7, "-9999"},
+		{"signed start", leaderLength + 7, "+0000"},
+		{"negative length", leaderLength + 3, "-001"},
+		{"letters in length", leaderLength + 3, "00x1"},
+		{"start past the end", leaderLength + 7, "99999"},
+		{"signed base address", 12, "+0000"},
+		{"negative base address", 12, "-0049"},
+	}
+	for _, tt := range tests {
+		data := testRecord(t)
+		copy(data[tt.offset:], tt.value)
+		var rec Record
+		if err := rec.UnmarshalBinary(data); !errors.Is(err, ErrInvalidRecord) {
+			t.Errorf("%s: got %v, want ErrInvalidRecord", tt.name, err)
+		}
+	}
+
+	var rec Record
+	if err := rec.UnmarshalBinary([]byte("00010")); !errors.Is(err, ErrInvalidRecord) {
+		t.Errorf("short record: got %v, want ErrInvalidRecord", err)
+	}
+}
@@ -0,0 +1,59 @@
+package marc
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// NamespaceMARCXML is the namespace of MARCXML documents
+const NamespaceMARCXML = "http://www.loc.gov/MARC21/slim"
+
+// Collection is a MARCXML collection of records
+type Collection struct {
+	XMLName xml.Name  `xml:"http://www.loc.gov/MARC21/slim collection"`
+	Records []*Record `xml:"record"`
+}
+
+// ParseXML read a MARCXML document, the root can be a collection or a
+// single record
+func ParseXML(r io.Reader) ([]*Record, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "collection":
+			var c Collection
+			if err := dec.DecodeElement(&c, &start); err != nil {
+				return nil, err
+			}
+			return c.Records, nil
+		case "record":
+			var rec Record
+			if err := dec.DecodeElement(&rec, &start); err != nil {
+				return nil, err
+			}
+			return []*Record{&rec}, nil
+		}
+	}
+}
+
+// WriteXML write the records as an indented MARCXML collection
+func WriteXML(w io.Writer, records ...*Record) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(Collection{Records: records}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
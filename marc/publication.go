@@ -0,0 +1,490 @@
+package marc
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/ohzqq/libopds2-go/opds2"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// defaultLeader describe a monograph of language material using UTF-8
+// without ISBD punctuation
+const defaultLeader = "00000nam a2200000 c 4500"
+
+// comics roles without relator code, stored only as terms
+var roleTerms = map[string]string{
+	"letterer": "letterer",
+	"penciler": "penciller",
+	"inker":    "inker",
+}
+
+// subjectSources map the 650 $2 source codes to subject schemes
+var subjectSources = map[string]string{
+	"bisacsh": opds2.SchemeBISAC,
+	"thema":   opds2.SchemeThema,
+	"bicssc":  opds2.SchemeBIC,
+	"ddc":     opds2.SchemeDDC,
+	"fast":    "http://id.worldcat.org/fast/",
+}
+
+// bibliographic MARC language codes differing from ISO 639-2/T
+var bibliographicLanguages = map[string]string{
+	"sqi": "alb", "hye": "arm", "eus": "baq", "mya": "bur", "zho": "chi",
+	"ces": "cze", "nld": "dut", "fra": "fre", "kat": "geo", "deu": "ger",
+	"ell": "gre", "isl": "ice", "mkd": "mac", "mri": "mao", "msa": "may",
+	"fas": "per", "ron": "rum", "slk": "slo", "bod": "tib", "cym": "wel",
+}
+
+// FromPublication convert a publication to a MARC 21 bibliographic record
+func FromPublication(pub *opds2.Publication) *Record {
+	m := &pub.Metadata
+	rec := &Record{Leader: defaultLeader}
+	if strings.HasSuffix(m.RDFType, "Audiobook") {
+		rec.Leader = rec.Leader[:6] + "i" + rec.Leader[7:]
+	}
+
+	if m.Identifier != "" {
+		rec.AddControlField("001", m.Identifier)
+	}
+	if m.Modified != nil {
+		rec.AddControlField("005", m.Modified.UTC().Format("20060102150405.0"))
+	}
+	rec.AddControlField("008", fixedField(m))
+
+	switch {
+	case strings.HasPrefix(m.Identifier, "urn:isbn:"):
+		rec.AddField("020", " ", " ", "a", strings.TrimPrefix(m.Identifier, "urn:isbn:"))
+	case strings.HasPrefix(m.Identifier, "urn:doi:"):
+		rec.AddField("024", "7", " ", "a", strings.TrimPrefix(m.Identifier, "urn:doi:"), "2", "doi")
+	case m.Identifier != "":
+		rec.AddField("024", "7", " ", "a", m.Identifier, "2", "uri")
+	}
+
+	for _, l := range m.Language {
+		rec.AddField("041", " ", " ", "a", marcLanguage(l))
+	}
+
+	lang := ""
+	if len(m.Language) > 0 {
+		lang = m.Language[0]
+	}
+	names := contributorsByRole(m)
+	mainEntry := false
+	for _, n := range names {
+		tag := "700"
+		if !mainEntry && n.Role == "author" {
+			tag = "100"
+			mainEntry = true
+		}
+		ind1 := "1"
+		name := n.Contributor.SortKey(lang)
+		if !strings.Contains(name, ",") && strings.Contains(name, " ") {
+			ind1 = "0"
+		}
+		subfields := []string{"a", name}
+		for _, t := range n.Terms {
+			subfields = append(subfields, "e", t)
+		}
+		for _, c := range n.Codes {
+			subfields = append(subfields, "4", c)
+		}
+		rec.AddField(tag, ind1, " ", subfields...)
+	}
+
+	ind1 := "0"
+	if mainEntry {
+		ind1 = "1"
+	}
+	rec.AddField("245", ind1, "0", "a", m.Title.String(), "c", m.Author.String())
+
+	var publisher, year string
+	if len(m.Publisher) > 0 {
+		publisher = m.Publisher[0].Name.String()
+	}
+	if m.PublicationDate != nil {
+		year = strconv.Itoa(m.PublicationDate.Year())
+	}
+	rec.AddField("264", " ", "1", "b", publisher, "c", year)
+
+	if m.BelongsTo != nil {
+		for _, s := range m.BelongsTo.Series {
+			name := s.Name.String()
+			var volume string
+			if s.Position != 0 {
+				volume = strconv.FormatFloat(s.Position, 'f', -1, 64)
+			}
+			rec.AddField("490", "1", " ", "a", name, "v", volume)
+			rec.AddField("830", " ", "0", "a", name, "v", volume)
+		}
+	}
+
+	rec.AddField("520", " ", " ", "a", m.Description)
+	rec.AddField("540", " ", " ", "a", m.Rights)
+
+	for _, s := range m.Subject {
+		ind2, source := "4", ""
+		switch s.Scheme {
+		case "":
+		case opds2.SchemeLCSH:
+			ind2 = "0"
+		default:
+			ind2, source = "7", s.Scheme
+			for code, scheme := range subjectSources {
+				if scheme == s.Scheme {
+					source = code
+				}
+			}
+		}
+		rec.AddField("650", " ", ind2, "a", s.Name, "0", s.Code, "2", source)
+	}
+
+	for _, l := range pub.Links {
+		if !l.IsAcquisition() {
+			continue
+		}
+		rec.AddField("856", "4", "0", "u", l.Href, "q", l.TypeLink, "y", l.Title)
+	}
+	for _, l := range pub.Images {
+		rec.AddField("856", "4", "2", "3", "Cover image", "u", l.Href, "q", l.TypeLink)
+	}
+
+	return rec
+}
+
+// Publication convert a bibliographic record to a publication
+func (r *Record) Publication() opds2.Publication {
+	pub := opds2.Publication{}
+	m := &pub.Metadata
+
+	m.RDFType = "http://schema.org/Book"
+	if len(r.Leader) > 6 && r.Leader[6] == 'i' {
+		m.RDFType = "http://schema.org/Audiobook"
+	}
+
+	for _, f := range r.Fields("020") {
+		if isbn := isbnValue(f.Subfield("a")); isbn != "" {
+			m.Identifier = "urn:isbn:" + isbn
+			break
+		}
+	}
+	if m.Identifier == "" {
+		for _, f := range r.Fields("024") {
+			switch f.Subfield("2") {
+			case "doi":
+				m.Identifier = "urn:doi:" + f.Subfield("a")
+			case "uri":
+				m.Identifier = f.Subfield("a")
+			}
+		}
+	}
+	if m.Identifier == "" {
+		m.Identifier = r.ControlField("001")
+	}
+	for _, f := range r.Fields("020") {
+		if isbn := isbnValue(f.Subfield("a")); isbn != "" {
+			m.AddAltIdentifier("urn:isbn:" + isbn)
+		}
+	}
+	for _, f := range r.Fields("024") {
+		if f.Subfield("2") == "doi" {
+			m.AddAltIdentifier("urn:doi:" + f.Subfield("a"))
+		}
+	}
+	if t, err := time.Parse("20060102150405.0", r.ControlField("005")); err == nil {
+		m.Modified = &t
+	}
+
+	for _, f := range r.Fields("245") {
+		title := trimPunctuation(f.Subfield("a"))
+		if sub := trimPunctuation(f.Subfield("b")); sub != "" {
+			title += ": " + sub
+		}
+		m.Title.SingleString = title
+	}
+
+	for _, tag := range []string{"100", "110", "700", "710"} {
+		for _, f := range r.Fields(tag) {
+			addName(m, f)
+		}
+	}
+
+	for _, f := range r.Fields("041") {
+		for _, code := range f.SubfieldValues("a") {
+			m.Language = append(m.Language, bcp47Language(code))
+		}
+	}
+	if len(m.Language) == 0 {
+		if f := r.ControlField("008"); len(f) >= 38 && strings.TrimSpace(f[35:38]) != "" {
+			m.Language = append(m.Language, bcp47Language(f[35:38]))
+		}
+	}
+
+	for _, tag := range []string{"264", "260"} {
+		for _, f := range r.Fields(tag) {
+			if tag == "264" && f.Ind2 != "1" {
+				continue
+			}
+			if p := trimPunctuation(f.Subfield("b")); p != "" && len(m.Publisher) == 0 {
+				m.Publisher = opds2.NewContributor(p)
+			}
+			if y, ok := parseYear(f.Subfield("c")); ok && m.PublicationDate == nil {
+				m.PublicationDate = &y
+			}
+		}
+	}
+
+	series := r.Fields("830")
+	if len(series) == 0 {
+		series = r.Fields("490")
+	}
+	for _, f := range series {
+		name := trimPunctuation(f.Subfield("a"))
+		if name == "" {
+			continue
+		}
+		col := pub.BelongsToSeries(name)
+		col.Position, _ = strconv.ParseFloat(strings.Trim(f.Subfield("v"), " .;v"), 64)
+	}
+
+	for _, f := range r.Fields("520") {
+		m.Description = strings.TrimSpace(f.Subfield("a"))
+		break
+	}
+	for _, f := range r.Fields("540") {
+		m.Rights = strings.TrimSpace(f.Subfield("a"))
+		break
+	}
+
+	for _, f := range r.Fields("650") {
+		s := &opds2.Subject{Name: trimPunctuation(f.Subfield("a")), Code: f.Subfield("0")}
+		switch f.Ind2 {
+		case "0":
+			s.Scheme = opds2.SchemeLCSH
+		case "7":
+			source := f.Subfield("2")
+			s.Scheme = source
+			if scheme, ok := subjectSources[source]; ok {
+				s.Scheme = scheme
+			}
+		}
+		if s.Name != "" {
+			m.Subject = append(m.Subject, s)
+		}
+	}
+
+	for _, f := range r.Fields("856") {
+		href := f.Subfield("u")
+		if href == "" {
+			continue
+		}
+		if f.Ind2 == "2" && strings.Contains(strings.ToLower(f.Subfield("3")), "cover") {
+			pub.AddImage(map[string]any{
+				"href": href,
+				"type": f.Subfield("q"),
+				"rel":  rel.Image,
+			})
+			continue
+		}
+		if f.Ind2 == "2" {
+			continue
+		}
+		l := &opds2.Link{
+			Href:     href,
+			TypeLink: f.Subfield("q"),
+			Title:    f.Subfield("y"),
+			Rel:      []string{rel.Acquisition},
+		}
+		pub.Links = append(pub.Links, l)
+	}
+
+	return pub
+}
+
+type roleName struct {
+	Contributor *opds2.Contributor
+	Role        string
+	Terms       []string
+	Codes       []string
+}
+
+// contributorsByRole list the contributors of the publication with their
+// relator terms and codes, the contributors of Contributor with each of
+// their roles
+func contributorsByRole(m *opds2.PublicationMetadata) []roleName {
+	var names []roleName
+	add := func(role string, cons opds2.Contributors) {
+		for _, c := range cons {
+			n := roleName{Contributor: c, Role: role}
+			roles := []string{role}
+			if role == "contributor" && len(c.Role) > 0 {
+				roles = c.Role
+			}
+			for _, r := range roles {
+				term, code := relatorTerm(r)
+				n.Terms = append(n.Terms, term)
+				if code != "" {
+					n.Codes = append(n.Codes, code)
+				}
+			}
+			names = append(names, n)
+		}
+	}
+	add("author", m.Author)
+	add("translator", m.Translator)
+	add("editor", m.Editor)
+	add("artist", m.Artist)
+	add("illustrator", m.Illustrator)
+	add("letterer", m.Letterer)
+	add("penciler", m.Penciler)
+	add("colorist", m.Colorist)
+	add("inker", m.Inker)
+	add("narrator", m.Narrator)
+	add("contributor", m.Contributor)
+	return names
+}
+
+// addName add the contributor of a 100/110/700/710 field to the list
+// matching its relator code or term, authors by default for main entries
+func addName(m *opds2.PublicationMetadata, f DataField) {
+	sortAs := trimPunctuation(f.Subfield("a"))
+	if sortAs == "" {
+		return
+	}
+	c := &opds2.Contributor{}
+	c.Name.SingleString = sortAs
+	if (f.Tag == "100" || f.Tag == "700") && f.Ind1 == "1" {
+		if last, first, ok := strings.Cut(sortAs, ", "); ok && !strings.Contains(first, ",") {
+			c.Name.SingleString = first + " " + last
+			c.SortAs = sortAs
+		}
+	}
+
+	var roles []string
+	for _, code := range f.SubfieldValues("4") {
+		if opds2.RelatorTerm(code) != "" {
+			roles = append(roles, code)
+		}
+	}
+	if len(roles) == 0 {
+		for _, term := range f.SubfieldValues("e") {
+			if term = trimPunctuation(term); term != "" {
+				roles = append(roles, opds2.NormalizeRole(term))
+			}
+		}
+	}
+	if len(roles) == 0 {
+		roles = []string{"contributor"}
+		if f.Tag == "100" || f.Tag == "110" {
+			roles = []string{"author"}
+		}
+	}
+
+	// the roles without list of their own are given to a copy in Contributor
+	var other *opds2.Contributor
+	for _, role := range roles {
+		if opds2.IsMetadataRole(role) {
+			m.AddContributor(role, c)
+			continue
+		}
+		if other == nil {
+			cp := *c
+			other = &cp
+		}
+		m.AddContributor(role, other)
+	}
+}
+
+// relatorTerm return the MARC term and relator code of a role, the comics
+// roles have no code
+func relatorTerm(role string) (string, string) {
+	role = opds2.NormalizeRole(role)
+	if t, ok := roleTerms[role]; ok {
+		return t, ""
+	}
+	if code := opds2.RelatorCode(role); code != "" {
+		return opds2.RelatorTerm(code), code
+	}
+	return role, ""
+}
+
+// fixedField build the 008 field: date entered, publication year and
+// language
+func fixedField(m *opds2.PublicationMetadata) string {
+	f := []byte(strings.Repeat(" ", 40))
+	entered := time.Now()
+	if m.Modified != nil {
+		entered = *m.Modified
+	}
+	copy(f[0:6], entered.Format("060102"))
+	if m.PublicationDate != nil {
+		f[6] = 's'
+		copy(f[7:11], m.PublicationDate.Format("2006"))
+	} else {
+		f[6] = 'n'
+		copy(f[7:11], "uuuu")
+	}
+	copy(f[15:18], "xx ")
+	if len(m.Language) > 0 {
+		copy(f[35:38], marcLanguage(m.Language[0]))
+	}
+	f[39] = 'd'
+	return string(f)
+}
+
+// marcLanguage convert a BCP 47 tag to a MARC language code
+func marcLanguage(tag string) string {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return tag
+	}
+	base, _ := t.Base()
+	code := base.ISO3()
+	if b, ok := bibliographicLanguages[code]; ok {
+		return b
+	}
+	return code
+}
+
+// bcp47Language convert a MARC language code to a BCP 47 tag
+func bcp47Language(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	for t, b := range bibliographicLanguages {
+		if b == code {
+			code = t
+		}
+	}
+	if t, err := language.Parse(code); err == nil {
+		return t.String()
+	}
+	return code
+}
+
+// isbnValue keep the ISBN of a 020 $a, dropping qualifiers like
+// "(pbk.)"
+func isbnValue(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " ("); i >= 0 {
+		s = s[:i]
+	}
+	return strings.ReplaceAll(s, "-", "")
+}
+
+func parseYear(s string) (time.Time, bool) {
+	var digits strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+			if digits.Len() == 4 {
+				t, err := time.Parse("2006", digits.String())
+				return t, err == nil
+			}
+		} else {
+			digits.Reset()
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,111 @@
+// Package marc read and write MARC 21 bibliographic records, in ISO 2709
+// binary and MARCXML, and convert them to and from OPDS publications
+// https://www.loc.gov/marc/bibliographic/
+package marc
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Record is a MARC 21 record
+type Record struct {
+	XMLName       xml.Name       `xml:"http://www.loc.gov/MARC21/slim record"`
+	Leader        string         `xml:"leader"`
+	ControlFields []ControlField `xml:"controlfield"`
+	DataFields    []DataField    `xml:"datafield"`
+}
+
+// ControlField is a 00X field without indicators nor subfields
+type ControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+// DataField is a field with two indicators and subfields
+type DataField struct {
+	Tag       string     `xml:"tag,attr"`
+	Ind1      string     `xml:"ind1,attr"`
+	Ind2      string     `xml:"ind2,attr"`
+	Subfields []Subfield `xml:"subfield"`
+}
+
+// Subfield is a coded value of a data field
+type Subfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ControlField return the value of the first control field with tag
+func (r *Record) ControlField(tag string) string {
+	for _, f := range r.ControlFields {
+		if f.Tag == tag {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Fields return the data fields with tag
+func (r *Record) Fields(tag string) []DataField {
+	var fields []DataField
+	for _, f := range r.DataFields {
+		if f.Tag == tag {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// AddControlField append a control field
+func (r *Record) AddControlField(tag string, value string) {
+	r.ControlFields = append(r.ControlFields, ControlField{Tag: tag, Value: value})
+}
+
+// AddField append a data field, subfields are given as code, value pairs
+// and empty values are skipped. The field is not added without subfields.
+func (r *Record) AddField(tag string, ind1 string, ind2 string, subfields ...string) {
+	f := DataField{Tag: tag, Ind1: ind1, Ind2: ind2}
+	for i := 0; i+1 < len(subfields); i += 2 {
+		if subfields[i+1] != "" {
+			f.Subfields = append(f.Subfields, Subfield{Code: subfields[i], Value: subfields[i+1]})
+		}
+	}
+	if len(f.Subfields) > 0 {
+		r.DataFields = append(r.DataFields, f)
+	}
+}
+
+// Subfield return the first value of the subfield code
+func (f DataField) Subfield(code string) string {
+	for _, s := range f.Subfields {
+		if s.Code == code {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// SubfieldValues return every values of the subfield code
+func (f DataField) SubfieldValues(code string) []string {
+	var values []string
+	for _, s := range f.Subfields {
+		if s.Code == code {
+			values = append(values, s.Value)
+		}
+	}
+	return values
+}
+
+// trimPunctuation remove the ISBD punctuation ending a subfield
+func trimPunctuation(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimRight(s, " /:;,=")
+	if strings.HasSuffix(s, ".") && !strings.HasSuffix(s, "..") {
+		// keep the period of initials like "Tolkien, J. R. R."
+		if i := strings.LastIndex(s, " "); i < 0 || len(s)-i > 3 {
+			s = strings.TrimSuffix(s, ".")
+		}
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,199 @@
+// Package mediatype parse and match the media types used by OPDS and
+// Readium links
+package mediatype
+
+import (
+	"mime"
+	"sort"
+	"strings"
+)
+
+// Media types of OPDS, Readium and the common publication formats
+const (
+	OPDS2                = "application/opds+json"
+	OPDS2Publication     = "application/opds-publication+json"
+	OPDSAuthentication   = "application/opds-authentication+json"
+	Atom                 = "application/atom+xml"
+	OPDS1                = "application/atom+xml;profile=opds-catalog"
+	OPDS1Acquisition     = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	OPDS1Navigation      = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	OPDS1Entry           = "application/atom+xml;type=entry;profile=opds-catalog"
+	OpenSearch           = "application/opensearchdescription+xml"
+	WebPub               = "application/webpub+json"
+	WebPubPackage        = "application/webpub+zip"
+	Audiobook            = "application/audiobook+json"
+	AudiobookPackage     = "application/audiobook+zip"
+	Divina               = "application/divina+json"
+	DivinaPackage        = "application/divina+zip"
+	LCPLicense           = "application/vnd.readium.lcp.license.v1.0+json"
+	LCPStatus            = "application/vnd.readium.license.status.v1.0+json"
+	LCPPDF               = "application/pdf+lcp"
+	LCPAudiobook         = "application/audiobook+lcp"
+	AdobeACSM            = "application/vnd.adobe.adept+xml"
+	EPUB                 = "application/epub+zip"
+	PDF                  = "application/pdf"
+	MOBI                 = "application/x-mobipocket-ebook"
+	AZW3                 = "application/vnd.amazon.ebook"
+	CBZ                  = "application/vnd.comicbook+zip"
+	CBR                  = "application/vnd.comicbook-rar"
+	FB2                  = "application/x-fictionbook+xml"
+	DjVu                 = "image/vnd.djvu"
+	Text                 = "text/plain"
+	OctetStream          = "application/octet-stream"
+	HTML                 = "text/html"
+	XHTML                = "application/xhtml+xml"
+	JSON                 = "application/json"
+	JSONLD               = "application/ld+json"
+	ProblemDetails       = "application/problem+json"
+	JPEG                 = "image/jpeg"
+	PNG                  = "image/png"
+	GIF                  = "image/gif"
+	WebP                 = "image/webp"
+	SVG                  = "image/svg+xml"
+	MP3                  = "audio/mpeg"
+	MP4Audio             = "audio/mp4"
+	OPDSAuthenticationV1 = "application/vnd.opds.authentication.v1.0+json"
+)
+
+// MediaType is a parsed media type, type, subtype and parameter names are
+// lower case
+type MediaType struct {
+	Type    string
+	Subtype string
+	Params  map[string]string
+}
+
+// Parse a media type like application/atom+xml;profile=opds-catalog
+func Parse(s string) (MediaType, error) {
+	base, params, err := mime.ParseMediaType(s)
+	if err != nil {
+		return MediaType{}, err
+	}
+	typ, sub, _ := strings.Cut(base, "/")
+	return MediaType{Type: typ, Subtype: sub, Params: params}, nil
+}
+
+// Base return type/subtype without the parameters
+func (m MediaType) Base() string {
+	if m.Type == "" {
+		return ""
+	}
+	return m.Type + "/" + m.Subtype
+}
+
+// Profile return the profile parameter
+func (m MediaType) Profile() string {
+	return m.Params["profile"]
+}
+
+// String format the media type with its parameters sorted by name
+func (m MediaType) String() string {
+	if m.Type == "" {
+		return ""
+	}
+	names := make([]string, 0, len(m.Params))
+	for k := range m.Params {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(m.Base())
+	for _, k := range names {
+		b.WriteString(";" + k + "=")
+		if v := m.Params[k]; strings.ContainsAny(v, " ;,\"=") {
+			b.WriteString(`"` + strings.ReplaceAll(v, `"`, `\"`) + `"`)
+		} else {
+			b.WriteString(v)
+		}
+	}
+	return b.String()
+}
+
+// Matches check if other is an instance of m: the type and subtype are the
+// same or m has a wildcard, and every parameter of m is set to the same
+// value in other, so application/atom+xml matches any OPDS 1 feed
+func (m MediaType) Matches(other MediaType) bool {
+	if m.Type != "*" && m.Type != other.Type {
+		return false
+	}
+	if m.Subtype != "*" && m.Subtype != other.Subtype {
+		return false
+	}
+	for k, v := range m.Params {
+		if !strings.EqualFold(other.Params[k], v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches parse both media types and check if mt is an instance of
+// pattern, it is false when one of them doesn't parse
+func Matches(pattern string, mt string) bool {
+	p, err := Parse(pattern)
+	if err != nil {
+		return false
+	}
+	m, err := Parse(mt)
+	if err != nil {
+		return false
+	}
+	return p.Matches(m)
+}
+
+// Base return the type/subtype of mt without parameters, lower cased, mt
+// is returned trimmed when it doesn't parse
+func Base(mt string) string {
+	m, err := Parse(mt)
+	if err != nil {
+		return strings.TrimSpace(mt)
+	}
+	return m.Base()
+}
+
+// IsOPDSFeed check if mt is an OPDS 2 feed or an OPDS 1 catalog
+func IsOPDSFeed(mt string) bool {
+	return Matches(OPDS2, mt) || Matches(OPDS1, mt)
+}
+
+// IsImage check if mt is an image
+func IsImage(mt string) bool {
+	return Matches("image/*", mt)
+}
+
+// IsAudio check if mt is an audio file
+func IsAudio(mt string) bool {
+	return Matches("audio/*", mt)
+}
+
+var names = map[string]string{
+	EPUB:             "EPUB",
+	PDF:              "PDF",
+	MOBI:             "MOBI",
+	AZW3:             "AZW3",
+	CBZ:              "CBZ",
+	CBR:              "CBR",
+	FB2:              "FB2",
+	AudiobookPackage: "Audiobook",
+	Audiobook:        "Audiobook",
+	WebPubPackage:    "Web Publication",
+	LCPPDF:           "PDF (LCP)",
+	LCPAudiobook:     "Audiobook (LCP)",
+	LCPLicense:       "LCP",
+	AdobeACSM:        "Adobe DRM",
+	MP3:              "MP3",
+	MP4Audio:         "M4B",
+	Text:             "Text",
+	HTML:             "HTML",
+}
+
+// Name return a short label for mt like EPUB, the subtype in upper case
+// when unknown
+func Name(mt string) string {
+	base := Base(mt)
+	if n, ok := names[base]; ok {
+		return n
+	}
+	_, sub, _ := strings.Cut(base, "/")
+	return strings.ToUpper(strings.TrimPrefix(sub, "x-"))
+}
@@ -0,0 +1,66 @@
+package onix
+
+import "github.com/ohzqq/libopds2-go/opds2"
+
+// Subject schemes URIs used for Subject.Scheme, indexed by ONIX list 27
+// subject scheme identifier
+var subjectSchemes = map[string]string{
+	"01": opds2.SchemeDDC,
+	"04": "http://id.loc.gov/authorities/classification/",
+	"10": opds2.SchemeBISAC,
+	"12": opds2.SchemeBIC,
+	"93": opds2.SchemeThema,
+	"94": "https://ns.editeur.org/thema/place/",
+	"95": "https://ns.editeur.org/thema/language/",
+	"96": "https://ns.editeur.org/thema/time/",
+	"97": "https://ns.editeur.org/thema/educational-purpose/",
+	"98": "https://ns.editeur.org/thema/interest-age/",
+	"99": "https://ns.editeur.org/thema/style/",
+}
+
+// contributor roles, ONIX list 17, mapped to the PublicationMetadata
+// contributor they are stored in
+var contributorRoles = map[string]string{
+	"A01": "author",
+	"A02": "author",
+	"A07": "artist",
+	"A12": "illustrator",
+	"A40": "inker",
+	"B01": "editor",
+	"B09": "editor",
+	"B11": "editor",
+	"B13": "editor",
+	"B06": "translator",
+	"E07": "narrator",
+}
+
+// productFormTypes give the media type of the file of digital products
+// from the product form detail, ONIX list 175
+var productFormTypes = map[string]string{
+	"E101": "application/epub+zip",
+	"E102": "application/vnd.ms-htmlhelp",
+	"E105": "text/html",
+	"E107": "application/pdf",
+	"E116": "application/vnd.amazon.ebook",
+	"E127": "application/x-mobipocket-ebook",
+	"A103": "audio/mpeg",
+	"A104": "audio/mp4",
+}
+
+// productFormDefaults give the media type from the product form when the
+// detail is missing, ONIX list 150
+var productFormDefaults = map[string]string{
+	"EA": "application/epub+zip",
+	"ED": "application/epub+zip",
+	"AJ": "audio/mpeg",
+	"AN": "audio/mpeg",
+}
+
+// identifier types, ONIX list 5
+const (
+	idProprietary = "01"
+	idISBN10      = "02"
+	idGTIN13      = "03"
+	idDOI         = "06"
+	idISBN13      = "15"
+)
@@ -0,0 +1,225 @@
+// Package onix import ONIX for Books 3.0 messages (reference tags) as OPDS
+// publications
+// https://www.editeur.org/83/Overview/
+package onix
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+
+	"github.com/ohzqq/libopds2-go/opds2"
+)
+
+// Message is an ONIX message, only the products are kept
+type Message struct {
+	XMLName  xml.Name  `xml:"ONIXMessage"`
+	Release  string    `xml:"release,attr"`
+	Products []Product `xml:"Product"`
+}
+
+// Product is a ONIX product record
+type Product struct {
+	RecordReference   string              `xml:"RecordReference"`
+	NotificationType  string              `xml:"NotificationType"`
+	Identifiers       []ProductIdentifier `xml:"ProductIdentifier"`
+	DescriptiveDetail DescriptiveDetail   `xml:"DescriptiveDetail"`
+	CollateralDetail  CollateralDetail    `xml:"CollateralDetail"`
+	PublishingDetail  PublishingDetail    `xml:"PublishingDetail"`
+	SupplyDetails     []SupplyDetail      `xml:"ProductSupply>SupplyDetail"`
+}
+
+// ProductIdentifier is an identifier of the product, ISBN, GTIN, DOI...
+type ProductIdentifier struct {
+	ProductIDType string `xml:"ProductIDType"`
+	IDValue       string `xml:"IDValue"`
+}
+
+// DescriptiveDetail describe the product form, titles, authorship and
+// subjects
+type DescriptiveDetail struct {
+	ProductComposition string        `xml:"ProductComposition"`
+	ProductForm        string        `xml:"ProductForm"`
+	ProductFormDetails []string      `xml:"ProductFormDetail"`
+	Collections        []Collection  `xml:"Collection"`
+	TitleDetails       []TitleDetail `xml:"TitleDetail"`
+	Contributors       []Contributor `xml:"Contributor"`
+	Languages          []Language    `xml:"Language"`
+	Subjects           []Subject     `xml:"Subject"`
+}
+
+// Collection is a series or a set the product belongs to
+type Collection struct {
+	CollectionType      string               `xml:"CollectionType"`
+	CollectionSequences []CollectionSequence `xml:"CollectionSequence"`
+	TitleDetails        []TitleDetail        `xml:"TitleDetail"`
+}
+
+// CollectionSequence is the position of the product in a collection
+type CollectionSequence struct {
+	CollectionSequenceType   string `xml:"CollectionSequenceType"`
+	CollectionSequenceNumber string `xml:"CollectionSequenceNumber"`
+}
+
+// TitleDetail is a title of the product or collection
+type TitleDetail struct {
+	TitleType     string         `xml:"TitleType"`
+	TitleElements []TitleElement `xml:"TitleElement"`
+}
+
+// TitleElement is a title at the product, collection or subcollection
+// level
+type TitleElement struct {
+	TitleElementLevel  string `xml:"TitleElementLevel"`
+	PartNumber         string `xml:"PartNumber"`
+	TitleText          string `xml:"TitleText"`
+	TitlePrefix        string `xml:"TitlePrefix"`
+	TitleWithoutPrefix string `xml:"TitleWithoutPrefix"`
+	Subtitle           string `xml:"Subtitle"`
+}
+
+// Contributor is a person or corporate body with its contribution roles
+type Contributor struct {
+	SequenceNumber     int      `xml:"SequenceNumber"`
+	ContributorRoles   []string `xml:"ContributorRole"`
+	PersonName         string   `xml:"PersonName"`
+	PersonNameInverted string   `xml:"PersonNameInverted"`
+	NamesBeforeKey     string   `xml:"NamesBeforeKey"`
+	KeyNames           string   `xml:"KeyNames"`
+	CorporateName      string   `xml:"CorporateName"`
+	Websites           []struct {
+		WebsiteLink string `xml:"WebsiteLink"`
+	} `xml:"Website"`
+}
+
+// Language is a language of the product and its role
+type Language struct {
+	LanguageRole string `xml:"LanguageRole"`
+	LanguageCode string `xml:"LanguageCode"`
+}
+
+// Subject is a subject code or heading in a scheme
+type Subject struct {
+	MainSubject             *struct{} `xml:"MainSubject"`
+	SubjectSchemeIdentifier string    `xml:"SubjectSchemeIdentifier"`
+	SubjectSchemeName       string    `xml:"SubjectSchemeName"`
+	SubjectCode             string    `xml:"SubjectCode"`
+	SubjectHeadingText      string    `xml:"SubjectHeadingText"`
+}
+
+// CollateralDetail hold descriptions and supporting resources
+type CollateralDetail struct {
+	TextContents        []TextContent        `xml:"TextContent"`
+	SupportingResources []SupportingResource `xml:"SupportingResource"`
+}
+
+// TextContent is a description, table of contents, review...
+type TextContent struct {
+	TextType string `xml:"TextType"`
+	Texts    []Text `xml:"Text"`
+}
+
+// Text keep the markup of xhtml texts
+type Text struct {
+	TextFormat string `xml:"textformat,attr"`
+	Language   string `xml:"language,attr"`
+	Content    string `xml:",innerxml"`
+}
+
+// SupportingResource is a cover, sample or other resource of the product
+type SupportingResource struct {
+	ResourceContentType string            `xml:"ResourceContentType"`
+	ResourceMode        string            `xml:"ResourceMode"`
+	ResourceVersions    []ResourceVersion `xml:"ResourceVersion"`
+}
+
+// ResourceVersion is a downloadable version of a resource
+type ResourceVersion struct {
+	ResourceForm     string   `xml:"ResourceForm"`
+	ResourceLinks    []string `xml:"ResourceLink"`
+	ResourceFeatures []struct {
+		ResourceVersionFeatureType string `xml:"ResourceVersionFeatureType"`
+		FeatureValue               string `xml:"FeatureValue"`
+	} `xml:"ResourceVersionFeature"`
+}
+
+// PublishingDetail hold the publisher, imprint and publishing dates
+type PublishingDetail struct {
+	Imprints []struct {
+		ImprintName string `xml:"ImprintName"`
+	} `xml:"Imprint"`
+	Publishers []struct {
+		PublishingRole string `xml:"PublishingRole"`
+		PublisherName  string `xml:"PublisherName"`
+	} `xml:"Publisher"`
+	PublishingDates []PublishingDate `xml:"PublishingDate"`
+}
+
+// PublishingDate is a date with its role and format
+type PublishingDate struct {
+	PublishingDateRole string `xml:"PublishingDateRole"`
+	Date               struct {
+		Format string `xml:"dateformat,attr"`
+		Value  string `xml:",chardata"`
+	} `xml:"Date"`
+}
+
+// SupplyDetail is the availability and prices from a supplier
+type SupplyDetail struct {
+	SupplierName        string  `xml:"Supplier>SupplierName"`
+	ProductAvailability string  `xml:"ProductAvailability"`
+	Prices              []Price `xml:"Price"`
+}
+
+// Price is a price of the product in a currency
+type Price struct {
+	PriceType    string        `xml:"PriceType"`
+	PriceAmount  opds2.Decimal `xml:"PriceAmount"`
+	CurrencyCode string        `xml:"CurrencyCode"`
+	Territory    string        `xml:"Territory>CountriesIncluded"`
+}
+
+// Parse read a whole ONIX message
+func Parse(r io.Reader) (*Message, error) {
+	var m Message
+	if err := xml.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// ParseFile read a whole ONIX message from a file
+func ParseFile(path string) (*Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Each decode the products of the message one by one and call fn for each
+// of them, large messages don't have to be kept in memory
+func Each(r io.Reader, fn func(*Product) error) error {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "Product" {
+			continue
+		}
+		var p Product
+		if err := dec.DecodeElement(&p, &start); err != nil {
+			return err
+		}
+		if err := fn(&p); err != nil {
+			return err
+		}
+	}
+}
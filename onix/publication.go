@@ -0,0 +1,408 @@
+package onix
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/opds2"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// Importer map ONIX products to publications
+type Importer struct {
+	// BaseURL is the prefix of the acquisition links, the record
+	// reference of the product is appended to it
+	BaseURL string
+	// AcquisitionURL override BaseURL to build the acquisition href
+	AcquisitionURL func(p *Product) string
+	// PriceTypes select the ONIX price types kept, consumer prices
+	// including tax by default
+	PriceTypes []string
+}
+
+// DefaultImporter is used by Publications
+var DefaultImporter = Importer{}
+
+// Publications map every product of the message with the default importer,
+// delete notifications are skipped
+func Publications(m *Message) []opds2.Publication {
+	return DefaultImporter.Publications(m)
+}
+
+// Publications map every product of the message, delete notifications are
+// skipped
+func (imp Importer) Publications(m *Message) []opds2.Publication {
+	var pubs []opds2.Publication
+	for i := range m.Products {
+		if m.Products[i].NotificationType == "05" {
+			continue
+		}
+		pubs = append(pubs, imp.Publication(&m.Products[i]))
+	}
+	return pubs
+}
+
+// Publication map a product to a publication
+func (imp Importer) Publication(p *Product) opds2.Publication {
+	pub := opds2.Publication{}
+	m := &pub.Metadata
+	d := &p.DescriptiveDetail
+
+	m.RDFType = "http://schema.org/Book"
+	if strings.HasPrefix(d.ProductForm, "A") {
+		m.RDFType = "http://schema.org/Audiobook"
+	}
+	m.Identifier = p.identifier()
+	for _, id := range p.altIdentifiers() {
+		m.AddAltIdentifier(id)
+	}
+	m.Title.SingleString = productTitle(d.TitleDetails, "01")
+
+	contributors := append([]Contributor(nil), d.Contributors...)
+	sort.SliceStable(contributors, func(i, j int) bool {
+		return contributors[i].SequenceNumber < contributors[j].SequenceNumber
+	})
+	for _, c := range contributors {
+		addContributor(m, c)
+	}
+
+	for _, l := range d.Languages {
+		if l.LanguageRole != "" && l.LanguageRole != "01" {
+			continue
+		}
+		code := strings.ToLower(l.LanguageCode)
+		if tag, err := language.Parse(code); err == nil {
+			code = tag.String()
+		}
+		m.Language = append(m.Language, code)
+	}
+
+	for _, s := range d.Subjects {
+		subject := &opds2.Subject{
+			Name:   s.SubjectHeadingText,
+			Code:   s.SubjectCode,
+			Scheme: subjectSchemes[s.SubjectSchemeIdentifier],
+		}
+		if subject.Scheme == "" {
+			subject.Scheme = s.SubjectSchemeName
+		}
+		if subject.Name == "" {
+			subject.Name = subject.Label()
+		}
+		if subject.Name == "" {
+			continue
+		}
+		if s.MainSubject != nil {
+			m.Subject = append(opds2.Subjects{subject}, m.Subject...)
+		} else {
+			m.Subject = append(m.Subject, subject)
+		}
+	}
+
+	for _, c := range d.Collections {
+		addCollection(&pub, c)
+	}
+
+	m.Description = p.description()
+
+	pd := &p.PublishingDetail
+	for _, pb := range pd.Publishers {
+		if pb.PublishingRole == "" || pb.PublishingRole == "01" {
+			m.Publisher = append(m.Publisher, opds2.NewContributor(pb.PublisherName)...)
+		}
+	}
+	for _, i := range pd.Imprints {
+		m.Imprint = append(m.Imprint, opds2.NewContributor(i.ImprintName)...)
+	}
+	for _, date := range pd.PublishingDates {
+		if date.PublishingDateRole != "01" && date.PublishingDateRole != "11" {
+			continue
+		}
+		if t, ok := parseDate(date.Date.Value, date.Date.Format); ok {
+			m.PublicationDate = &t
+			break
+		}
+	}
+
+	for _, r := range p.CollateralDetail.SupportingResources {
+		// front cover image
+		if r.ResourceContentType != "01" || r.ResourceMode != "03" {
+			continue
+		}
+		for _, v := range r.ResourceVersions {
+			for _, href := range v.ResourceLinks {
+				pub.AddImage(map[string]any{
+					"href": strings.TrimSpace(href),
+					"type": v.mediaType(href),
+					"rel":  rel.Image,
+				})
+			}
+		}
+	}
+
+	imp.addAcquisitions(&pub, p)
+
+	return pub
+}
+
+// addAcquisitions add a buy link with the prices of the supply details, one
+// per currency, products without price get an acquisition link
+func (imp Importer) addAcquisitions(pub *opds2.Publication, p *Product) {
+	href := imp.acquisitionURL(p)
+	if href == "" {
+		return
+	}
+	typeLink := p.mediaType()
+
+	priceTypes := imp.PriceTypes
+	if len(priceTypes) == 0 {
+		priceTypes = []string{"02", "04", "42"}
+	}
+
+	var prices opds2.Prices
+	seen := make(map[string]bool)
+	for _, sd := range p.SupplyDetails {
+		for _, pr := range sd.Prices {
+			if !containsString(priceTypes, pr.PriceType) || seen[pr.CurrencyCode] {
+				continue
+			}
+			seen[pr.CurrencyCode] = true
+			prices = append(prices, opds2.Price{Currency: pr.CurrencyCode, Value: pr.PriceAmount})
+		}
+	}
+
+	if len(prices) == 0 {
+		pub.AddLink(map[string]any{
+			"href": href,
+			"type": typeLink,
+			"rel":  rel.Acquisition,
+		})
+		return
+	}
+	pub.Links = append(pub.Links, &opds2.Link{
+		Href:       href,
+		TypeLink:   typeLink,
+		Rel:        []string{rel.AcquisitionBuy},
+		Properties: &opds2.Properties{Price: prices},
+	})
+}
+
+func (imp Importer) acquisitionURL(p *Product) string {
+	if imp.AcquisitionURL != nil {
+		return imp.AcquisitionURL(p)
+	}
+	if imp.BaseURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(imp.BaseURL, "/") + "/" + url.PathEscape(p.RecordReference)
+}
+
+// identifier return the first valid ISBN as an urn, normalized to 13
+// digits like the alternate identifiers, then the DOI and finally the
+// record reference
+func (p *Product) identifier() string {
+	ids := make(map[string]string)
+	for _, id := range p.Identifiers {
+		ids[id.ProductIDType] = strings.ReplaceAll(strings.TrimSpace(id.IDValue), "-", "")
+	}
+	// a GTIN-13 is an ISBN when it starts with 978 or 979, as checked by
+	// NormalizeISBN
+	for _, t := range []string{idISBN13, idGTIN13, idISBN10} {
+		if isbn, err := opds2.NormalizeISBN(ids[t]); err == nil {
+			return "urn:isbn:" + isbn
+		}
+	}
+	if doi := ids[idDOI]; doi != "" {
+		return "urn:doi:" + doi
+	}
+	return p.RecordReference
+}
+
+// altIdentifiers return the ISBN, GTIN and DOI of the product as URN, the
+// one used as identifier is left out by AddAltIdentifier
+func (p *Product) altIdentifiers() []string {
+	var ids []string
+	for _, id := range p.Identifiers {
+		value := strings.TrimSpace(id.IDValue)
+		switch id.ProductIDType {
+		case idISBN13, idISBN10, idGTIN13:
+			if isbn, err := opds2.NormalizeISBN(value); err == nil {
+				ids = append(ids, "urn:isbn:"+isbn)
+			}
+		case idDOI:
+			ids = append(ids, "urn:doi:"+value)
+		}
+	}
+	return ids
+}
+
+func (p *Product) mediaType() string {
+	d := &p.DescriptiveDetail
+	for _, detail := range d.ProductFormDetails {
+		if t, ok := productFormTypes[detail]; ok {
+			return t
+		}
+	}
+	return productFormDefaults[d.ProductForm]
+}
+
+// description prefer the main description to the short one
+func (p *Product) description() string {
+	var short string
+	for _, tc := range p.CollateralDetail.TextContents {
+		if len(tc.Texts) == 0 {
+			continue
+		}
+		switch tc.TextType {
+		case "03":
+			return strings.TrimSpace(tc.Texts[0].Content)
+		case "02":
+			short = strings.TrimSpace(tc.Texts[0].Content)
+		}
+	}
+	return short
+}
+
+func (v ResourceVersion) mediaType(href string) string {
+	for _, f := range v.ResourceFeatures {
+		// file format, ONIX list 178
+		if f.ResourceVersionFeatureType == "01" {
+			switch f.FeatureValue {
+			case "D502":
+				return mediatype.JPEG
+			case "D503":
+				return mediatype.PNG
+			case "D504":
+				return mediatype.GIF
+			}
+		}
+	}
+	switch strings.ToLower(href[strings.LastIndex(href, ".")+1:]) {
+	case "png":
+		return mediatype.PNG
+	case "gif":
+		return mediatype.GIF
+	}
+	return mediatype.JPEG
+}
+
+// productTitle return the product level title of type titleType
+func productTitle(details []TitleDetail, titleType string) string {
+	for _, td := range details {
+		if td.TitleType != titleType {
+			continue
+		}
+		for _, te := range td.TitleElements {
+			if te.TitleElementLevel == "01" || te.TitleElementLevel == "" {
+				return te.title()
+			}
+		}
+	}
+	return ""
+}
+
+func (te TitleElement) title() string {
+	if te.TitleText != "" {
+		return strings.TrimSpace(te.TitleText)
+	}
+	return strings.TrimSpace(te.TitlePrefix + " " + te.TitleWithoutPrefix)
+}
+
+// addCollection store collections with a position as series
+func addCollection(pub *opds2.Publication, c Collection) {
+	var name, part string
+	for _, td := range c.TitleDetails {
+		for _, te := range td.TitleElements {
+			if te.TitleElementLevel == "02" || te.TitleElementLevel == "" {
+				name = te.title()
+				part = te.PartNumber
+			}
+		}
+	}
+	if name == "" {
+		return
+	}
+	for _, s := range c.CollectionSequences {
+		if part == "" {
+			part = s.CollectionSequenceNumber
+		}
+	}
+
+	if part == "" {
+		pub.BelongsToCollection(name)
+		return
+	}
+	col := pub.BelongsToSeries(name)
+	col.Position, _ = strconv.ParseFloat(strings.TrimSpace(part), 64)
+}
+
+func addContributor(m *opds2.PublicationMetadata, c Contributor) {
+	con := &opds2.Contributor{}
+	switch {
+	case c.PersonName != "":
+		con.Name.SingleString = c.PersonName
+	case c.KeyNames != "":
+		con.Name.SingleString = strings.TrimSpace(c.NamesBeforeKey + " " + c.KeyNames)
+	default:
+		con.Name.SingleString = c.CorporateName
+	}
+	if con.Name.SingleString == "" {
+		return
+	}
+	switch {
+	case c.PersonNameInverted != "":
+		con.SortAs = c.PersonNameInverted
+	case c.KeyNames != "" && c.NamesBeforeKey != "":
+		con.SortAs = c.KeyNames + ", " + c.NamesBeforeKey
+	}
+	for _, w := range c.Websites {
+		con.Links = append(con.Links, &opds2.Link{Href: w.WebsiteLink})
+	}
+
+	// the roles without list of their own are given to a copy in Contributor
+	var other *opds2.Contributor
+	for _, role := range c.ContributorRoles {
+		if r := contributorRoles[role]; r != "" {
+			m.AddContributor(r, con)
+			continue
+		}
+		if other == nil {
+			cp := *con
+			other = &cp
+		}
+		m.AddContributor(role, other)
+	}
+}
+
+// parseDate parse ONIX dates, YYYYMMDD unless dateformat say otherwise
+func parseDate(value string, format string) (time.Time, bool) {
+	layouts := map[string]string{
+		"":   "20060102",
+		"00": "20060102",
+		"01": "200601",
+		"05": "2006",
+		"13": "20060102T150405",
+		"14": "20060102T150405Z0700",
+	}
+	layout, ok := layouts[format]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(layout, strings.TrimSpace(value))
+	return t, err == nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -4,112 +4,233 @@ package opds1
 
 import (
 	"encoding/xml"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
 	"time"
 )
 
+// Namespaces used by OPDS 1.x feeds
+const (
+	NamespaceAtom       = "http://www.w3.org/2005/Atom"
+	NamespaceDC         = "http://purl.org/dc/terms/"
+	NamespaceOPDS       = "http://opds-spec.org/2010/catalog"
+	NamespaceOpenSearch = "http://a9.com/-/spec/opensearch/1.1/"
+	NamespaceSchema     = "http://schema.org/"
+	NamespaceXML        = "http://www.w3.org/XML/1998/namespace"
+)
+
 // Feed root element for acquisition or navigation feed
 type Feed struct {
+	XMLName      xml.Name  `xml:"http://www.w3.org/2005/Atom feed"`
+	Base         string    `xml:"http://www.w3.org/XML/1998/namespace base,attr,omitempty"`
 	ID           string    `xml:"id"`
 	Title        string    `xml:"title"`
 	Updated      time.Time `xml:"updated"`
 	Entries      []Entry   `xml:"entry"`
 	Links        []Link    `xml:"link"`
-	TotalResults int       `xml:"totalResults"`
-	ItemsPerPage int       `xml:"itemsPerPage"`
+	TotalResults int       `xml:"http://a9.com/-/spec/opensearch/1.1/ totalResults,omitempty"`
+	ItemsPerPage int       `xml:"http://a9.com/-/spec/opensearch/1.1/ itemsPerPage,omitempty"`
+	// URL the feed was read from, relative hrefs resolve against it
+	URL string `xml:"-"`
 }
 
 // Link link to different resources
 type Link struct {
-	Rel                 string                `xml:"rel,attr"`
+	Base                string                `xml:"http://www.w3.org/XML/1998/namespace base,attr,omitempty"`
+	Rel                 string                `xml:"rel,attr,omitempty"`
 	Href                string                `xml:"href,attr"`
-	TypeLink            string                `xml:"type,attr"`
-	Title               string                `xml:"title,attr"`
-	FacetGroup          string                `xml:"facetGroup,attr"`
-	Count               int                   `xml:"count,attr"`
-	Price               Price                 `xml:"price"`
-	IndirectAcquisition []IndirectAcquisition `xml:"indirectAcquisition"`
+	TypeLink            string                `xml:"type,attr,omitempty"`
+	Title               string                `xml:"title,attr,omitempty"`
+	FacetGroup          string                `xml:"http://opds-spec.org/2010/catalog facetGroup,attr,omitempty"`
+	ActiveFacet         bool                  `xml:"http://opds-spec.org/2010/catalog activeFacet,attr,omitempty"`
+	Count               int                   `xml:"http://purl.org/syndication/thread/1.0 count,attr,omitempty"`
+	Price               []Price               `xml:"http://opds-spec.org/2010/catalog price"`
+	IndirectAcquisition []IndirectAcquisition `xml:"http://opds-spec.org/2010/catalog indirectAcquisition"`
+	Availability        *Availability         `xml:"http://opds-spec.org/2010/catalog availability,omitempty"`
+	Holds               *Holds                `xml:"http://opds-spec.org/2010/catalog holds,omitempty"`
+	Copies              *Copies               `xml:"http://opds-spec.org/2010/catalog copies,omitempty"`
+}
+
+// Availability of a lending acquisition link, status is available,
+// unavailable, reserved or ready, since and until are RFC 3339 dates
+type Availability struct {
+	Status string `xml:"status,attr"`
+	Since  string `xml:"since,attr,omitempty"`
+	Until  string `xml:"until,attr,omitempty"`
+}
+
+// Holds is the queue of holds on a lending acquisition link
+type Holds struct {
+	Total    int `xml:"total,attr"`
+	Position int `xml:"position,attr,omitempty"`
+}
+
+// Copies is the number of copies of a lending acquisition link
+type Copies struct {
+	Total     int `xml:"total,attr"`
+	Available int `xml:"available,attr"`
 }
 
 // Author represent the feed author or the entry author
 type Author struct {
 	Name string `xml:"name"`
-	URI  string `xml:"uri"`
+	URI  string `xml:"uri,omitempty"`
 }
 
 // Entry an atom entry in the feed
 type Entry struct {
+	Base       string     `xml:"http://www.w3.org/XML/1998/namespace base,attr,omitempty"`
 	Title      string     `xml:"title"`
 	ID         string     `xml:"id"`
-	Identifier string     `xml:"identifier"`
+	Identifier string     `xml:"http://purl.org/dc/terms/ identifier,omitempty"`
 	Updated    *time.Time `xml:"updated"`
-	Rights     string     `xml:"rights"`
-	Publisher  string     `xml:"publisher"`
+	Rights     string     `xml:"rights,omitempty"`
+	Publisher  string     `xml:"http://purl.org/dc/terms/ publisher,omitempty"`
 	Author     []Author   `xml:"author,omitempty"`
-	Language   string     `xml:"language"`
-	Issued     string     `xml:"issued"` // Check for format
+	Language   string     `xml:"http://purl.org/dc/terms/ language,omitempty"`
+	Issued     string     `xml:"http://purl.org/dc/terms/ issued,omitempty"` // Check for format
 	Published  *time.Time `xml:"published"`
 	Category   []Category `xml:"category,omitempty"`
 	Links      []Link     `xml:"link,omitempty"`
 	Summary    Content    `xml:"summary"`
 	Content    Content    `xml:"content"`
-	Series     []Serie    `xml:"Series"`
+	Series     []Serie    `xml:"http://schema.org/ Series"`
 }
 
 // Content content tag in an entry, the type will be html or text
 type Content struct {
 	Content     string `xml:",cdata"`
-	ContentType string `xml:"type,attr"`
+	ContentType string `xml:"type,attr,omitempty"`
 }
 
 // Category represent the book category with scheme and term to machine
 // handling
 type Category struct {
-	Scheme string `xml:"scheme,attr"`
+	Scheme string `xml:"scheme,attr,omitempty"`
 	Term   string `xml:"term,attr"`
-	Label  string `xml:"label,attr"`
+	Label  string `xml:"label,attr,omitempty"`
 }
 
-// Price represent the book price
+// Price represent the book price in a currency, the value is kept as
+// written in the feed
 type Price struct {
-	CurrencyCode string  `xml:"currencycode,attr"`
-	Value        float64 `xml:",cdata"`
+	CurrencyCode string `xml:"currencycode,attr"`
+	Value        string `xml:",chardata"`
 }
 
 // IndirectAcquisition represent the link mostly for buying or borrowing
 // a book
 type IndirectAcquisition struct {
 	TypeAcquisition     string                `xml:"type,attr"`
-	IndirectAcquisition []IndirectAcquisition `xml:"indirectAcquisition"`
+	IndirectAcquisition []IndirectAcquisition `xml:"http://opds-spec.org/2010/catalog indirectAcquisition"`
 }
 
 // Serie store serie information from schema.org
 type Serie struct {
 	Name     string  `xml:"name,attr"`
-	URL      string  `xml:"url,attr"`
-	Position float32 `xml:"position,attr"`
+	URL      string  `xml:"url,attr,omitempty"`
+	Position float32 `xml:"position,attr,omitempty"`
+}
+
+// MarshalXML skip empty content so an entry without summary or content
+// doesn't produce empty elements
+func (c Content) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if c.Content == "" {
+		return nil
+	}
+	type content Content
+	return e.EncodeElement(content(c), start)
+}
+
+// MarshalXML skip the price element when there is no currency
+func (p Price) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if p.CurrencyCode == "" {
+		return nil
+	}
+	type price Price
+	return e.EncodeElement(price(p), start)
 }
 
 // ParseURL take a url in entry and parse the feed
 func ParseURL(url string) (*Feed, error) {
-	var feed Feed
-
 	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
+	return ParseRequest(request)
+}
+
+// ParseRequest send the request and parse the feed of the response, the
+// request can carry headers like Accept-Language or Authorization
+func ParseRequest(request *http.Request) (*Feed, error) {
 	res, errReq := http.DefaultClient.Do(request)
 	if errReq != nil {
 		return nil, errReq
 	}
+	defer res.Body.Close()
 
 	buff, errRead := ioutil.ReadAll(res.Body)
 	if errRead != nil {
 		return nil, errRead
 	}
 
-	xml.Unmarshal(buff, &feed)
+	feed, err := ParseBuffer(buff)
+	feed.URL = res.Request.URL.String()
+	return feed, err
+}
+
+// ParseFile parse opds1 from a file on filesystem
+func ParseFile(filePath string) (*Feed, error) {
+	f, err := os.ReadFile(filePath)
+	if err != nil {
+		return &Feed{}, err
+	}
+
+	feed, err := ParseBuffer(f)
+	feed.URL = fileURL(filePath)
+	return feed, err
+}
+
+// ParseBuffer parse opds1 feed from a buffer of byte usually get
+// from a file or url
+func ParseBuffer(buff []byte) (*Feed, error) {
+	var feed Feed
+
+	err := xml.Unmarshal(buff, &feed)
+	if err != nil {
+		return &feed, err
+	}
 
 	return &feed, nil
 }
+
+// Write encode the feed as an indented Atom document
+func (feed *Feed) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Write encode the entry as a standalone Atom entry document
+func (entry *Entry) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	start := xml.StartElement{Name: xml.Name{Space: NamespaceAtom, Local: "entry"}}
+	if err := enc.EncodeElement(entry, start); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,78 @@
+package opds1
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// BaseURL return the base of the relative hrefs of the feed, its xml:base
+// resolved against the URL the feed was read from
+func (feed *Feed) BaseURL() string {
+	return resolve(feed.URL, feed.Base)
+}
+
+// ResolveHref return the href of link resolved against the xml:base in
+// scope and the URL of the feed, entry is nil for the links of the feed
+func (feed *Feed) ResolveHref(entry *Entry, link *Link) string {
+	base := feed.BaseURL()
+	if entry != nil {
+		base = resolve(base, entry.Base)
+	}
+	base = resolve(base, link.Base)
+	return resolve(base, link.Href)
+}
+
+// ResolveLinks rewrite every href of the feed as returned by ResolveHref
+// and remove the xml:base attributes, the base of the feed is kept in URL
+func (feed *Feed) ResolveLinks() {
+	for i := range feed.Links {
+		l := &feed.Links[i]
+		l.Href, l.Base = feed.ResolveHref(nil, l), ""
+	}
+	for i := range feed.Entries {
+		e := &feed.Entries[i]
+		for j := range e.Links {
+			l := &e.Links[j]
+			l.Href, l.Base = feed.ResolveHref(e, l), ""
+		}
+		e.Base = ""
+	}
+	feed.URL, feed.Base = feed.BaseURL(), ""
+}
+
+// resolve ref against base, a relative base keeps the result relative and
+// the variables of a templated ref are kept as is
+func resolve(base string, ref string) string {
+	if base == "" {
+		return ref
+	}
+	if ref == "" {
+		return base
+	}
+	template := ""
+	if i := strings.Index(ref, "{"); i >= 0 {
+		ref, template = ref[:i], ref[i:]
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref + template
+	}
+	r, err := url.Parse(ref)
+	if err != nil || r.IsAbs() {
+		return ref + template
+	}
+	if !b.IsAbs() && !strings.HasPrefix(base, "/") && !strings.HasPrefix(ref, "/") {
+		return base[:strings.LastIndex(base, "/")+1] + ref + template
+	}
+	return b.ResolveReference(r).String() + template
+}
+
+// fileURL return the file URL of a local path
+func fileURL(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
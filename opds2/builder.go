@@ -0,0 +1,516 @@
+package opds2
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// FeedBuilder build a feed step by step, the errors found along the way
+// are returned by Build
+type FeedBuilder struct {
+	feed Feed
+	errs []error
+}
+
+// PublicationBuilder build a publication step by step, the errors found
+// along the way are returned by Build
+type PublicationBuilder struct {
+	pub  Publication
+	errs []error
+}
+
+// NewFeedBuilder start building a feed
+func NewFeedBuilder() *FeedBuilder {
+	b := &FeedBuilder{feed: New("")}
+	b.feed.Links = Links{}
+	return b
+}
+
+func (b *FeedBuilder) errorf(format string, args ...any) *FeedBuilder {
+	b.errs = append(b.errs, fmt.Errorf("opds2: "+format, args...))
+	return b
+}
+
+// Title set the title of the feed in lang, an empty lang set the title
+// used for every language
+func (b *FeedBuilder) Title(lang string, title string) *FeedBuilder {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return b.errorf("empty title")
+	}
+	if lang != "" {
+		if _, err := language.Parse(lang); err != nil {
+			return b.errorf("invalid title language %q", lang)
+		}
+	}
+	b.feed.Metadata.Title.Set(lang, title)
+	return b
+}
+
+// Type set the @type of the feed metadata
+func (b *FeedBuilder) Type(rdfType string) *FeedBuilder {
+	b.feed.Metadata.RDFType = rdfType
+	return b
+}
+
+// Modified set the last modification time of the feed
+func (b *FeedBuilder) Modified(t time.Time) *FeedBuilder {
+	b.feed.Metadata.Modified = &t
+	return b
+}
+
+// Link add a link to the feed
+func (b *FeedBuilder) Link(href string, rel string, typeLink string) *FeedBuilder {
+	if err := checkHref(href, false); err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	b.feed.AddLink(href, rel, typeLink, false)
+	return b
+}
+
+// Self set the self link of the feed
+func (b *FeedBuilder) Self(href string) *FeedBuilder {
+	if b.feed.Links.FilterByRel(rel.Self) != nil {
+		return b.errorf("duplicate self link %s", href)
+	}
+	return b.Link(href, rel.Self, mediatype.OPDS2)
+}
+
+// Start add the link to the root feed of the catalog
+func (b *FeedBuilder) Start(href string) *FeedBuilder {
+	return b.Link(href, rel.Start, mediatype.OPDS2)
+}
+
+// Up add the link to the parent feed
+func (b *FeedBuilder) Up(href string) *FeedBuilder {
+	return b.Link(href, rel.Up, mediatype.OPDS2)
+}
+
+// Search add the templated search link, the template must have a variable
+// like {?query}
+func (b *FeedBuilder) Search(template string) *FeedBuilder {
+	if !strings.Contains(template, "{") {
+		return b.errorf("search link %s is not a template", template)
+	}
+	if err := checkHref(template, true); err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	b.feed.AddLink(template, rel.Search, mediatype.OPDS2, true)
+	return b
+}
+
+// Paginate set the pagination metadata and add the first, previous, next
+// and last links, pageURL return the href of a page starting at 1
+func (b *FeedBuilder) Paginate(total int, perPage int, page int, pageURL func(page int) string) *FeedBuilder {
+	if perPage <= 0 {
+		return b.errorf("items per page must be positive, got %d", perPage)
+	}
+	pages := (total + perPage - 1) / perPage
+	if pages < 1 {
+		pages = 1
+	}
+	if page < 1 || page > pages {
+		return b.errorf("page %d out of range 1-%d", page, pages)
+	}
+
+	var next, prev, first, last string
+	if pages > 1 {
+		first, last = pageURL(1), pageURL(pages)
+		if page > 1 {
+			prev = pageURL(page - 1)
+		}
+		if page < pages {
+			next = pageURL(page + 1)
+		}
+	}
+	b.feed.AddPagination(total, perPage, page, next, prev, first, last)
+	return b
+}
+
+// Navigation add a navigation link
+func (b *FeedBuilder) Navigation(title string, href string) *FeedBuilder {
+	if title == "" {
+		return b.errorf("navigation link %s has no title", href)
+	}
+	if err := checkHref(href, false); err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	b.feed.AddNavigation(title, href, "", mediatype.OPDS2)
+	return b
+}
+
+// Facet add a facet link to the group, active mark the facet of the
+// current feed
+func (b *FeedBuilder) Facet(group string, title string, href string, count int, active bool) *FeedBuilder {
+	if group == "" || title == "" {
+		return b.errorf("facet %s has no group or title", href)
+	}
+	if err := checkHref(href, false); err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	l := &Link{Href: href, TypeLink: mediatype.OPDS2, Title: title}
+	if count > 0 {
+		l.Properties = &Properties{NumberOfItems: count}
+	}
+	if active {
+		l.Rel = []string{rel.Self}
+	}
+	b.feed.AddFacet(l, group)
+	return b
+}
+
+// Publication add publications, built with a PublicationBuilder
+func (b *FeedBuilder) Publication(pubs ...Publication) *FeedBuilder {
+	b.feed.Publications = append(b.feed.Publications, pubs...)
+	return b
+}
+
+// Group add a group of publications linking to the full collection at href
+func (b *FeedBuilder) Group(title string, href string, pubs ...Publication) *FeedBuilder {
+	if title == "" {
+		return b.errorf("group has no title")
+	}
+	if len(pubs) == 0 {
+		return b.errorf("group %q has no publication", title)
+	}
+	g := Group{Publications: pubs}
+	g.Metadata.Title.SingleString = title
+	if href != "" {
+		if err := checkHref(href, false); err != nil {
+			b.errs = append(b.errs, err)
+			return b
+		}
+		g.Links = Links{{Href: href, TypeLink: mediatype.OPDS2, Rel: []string{rel.Self}, Title: title}}
+	}
+	b.feed.Groups = append(b.feed.Groups, g)
+	return b
+}
+
+// NavigationGroup add a group of navigation links
+func (b *FeedBuilder) NavigationGroup(title string, links ...*Link) *FeedBuilder {
+	if title == "" {
+		return b.errorf("group has no title")
+	}
+	if len(links) == 0 {
+		return b.errorf("group %q has no navigation link", title)
+	}
+	for _, l := range links {
+		if l.Title == "" {
+			return b.errorf("navigation link %s has no title", l.Href)
+		}
+	}
+	g := Group{Navigation: links}
+	g.Metadata.Title.SingleString = title
+	b.feed.Groups = append(b.feed.Groups, g)
+	return b
+}
+
+// Build return the feed, or the errors found while building it, a feed
+// needs a title, a self link and at least one collection
+func (b *FeedBuilder) Build() (Feed, error) {
+	errs := b.errs
+	if b.feed.Metadata.Title.IsEmpty() {
+		errs = append(errs, errors.New("opds2: feed has no title"))
+	}
+	if b.feed.Links.FilterByRel(rel.Self) == nil {
+		errs = append(errs, errors.New("opds2: feed has no self link"))
+	}
+	if len(b.feed.Navigation) == 0 && len(b.feed.Publications) == 0 && len(b.feed.Groups) == 0 {
+		errs = append(errs, errors.New("opds2: feed has no navigation, publications or groups"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Feed{}, err
+	}
+	return b.feed, nil
+}
+
+// NewPublicationBuilder start building a publication
+func NewPublicationBuilder() *PublicationBuilder {
+	return &PublicationBuilder{}
+}
+
+func (b *PublicationBuilder) errorf(format string, args ...any) *PublicationBuilder {
+	b.errs = append(b.errs, fmt.Errorf("opds2: "+format, args...))
+	return b
+}
+
+// Title set the title in lang, an empty lang set the title used for every
+// language
+func (b *PublicationBuilder) Title(lang string, title string) *PublicationBuilder {
+	if strings.TrimSpace(title) == "" {
+		return b.errorf("empty title")
+	}
+	if lang != "" {
+		if _, err := language.Parse(lang); err != nil {
+			return b.errorf("invalid title language %q", lang)
+		}
+	}
+	b.pub.Metadata.Title.Set(lang, title)
+	return b
+}
+
+// SortAs set the string used to sort the publication by title
+func (b *PublicationBuilder) SortAs(s string) *PublicationBuilder {
+	b.pub.Metadata.SortAs = s
+	return b
+}
+
+// Identifier set the identifier, an URI like urn:isbn:9780306406157, bare
+// ISBN, UUID and DOI are accepted and stored as URN, the check digit of
+// ISBN is verified
+func (b *PublicationBuilder) Identifier(id string) *PublicationBuilder {
+	parsed, err := ParseIdentifier(id)
+	if err != nil {
+		return b.errorf("invalid identifier %q", id)
+	}
+	if parsed.Type == IdentifierOther {
+		if _, err := NormalizeISBN(id); err == ErrChecksum {
+			return b.errorf("identifier %q has an invalid ISBN check digit", id)
+		}
+		return b.errorf("identifier %q is not an URI", id)
+	}
+	b.pub.Metadata.Identifier = parsed.URN()
+	return b
+}
+
+// AltIdentifier add alternate identifiers, the ISBN of another edition
+// or the identifier of the publication in another catalog
+func (b *PublicationBuilder) AltIdentifier(ids ...string) *PublicationBuilder {
+	for _, id := range ids {
+		parsed, err := ParseIdentifier(id)
+		if err != nil {
+			return b.errorf("invalid alternate identifier %q", id)
+		}
+		if parsed.Type == IdentifierOther {
+			if _, err := NormalizeISBN(id); err == ErrChecksum {
+				return b.errorf("alternate identifier %q has an invalid ISBN check digit", id)
+			}
+			return b.errorf("alternate identifier %q is not an URI", id)
+		}
+		b.pub.Metadata.AddAltIdentifier(parsed.URN())
+	}
+	return b
+}
+
+// Type set the @type of the publication, http://schema.org/EBook when
+// unset
+func (b *PublicationBuilder) Type(rdfType string) *PublicationBuilder {
+	b.pub.Metadata.RDFType = rdfType
+	return b
+}
+
+// Author add authors
+func (b *PublicationBuilder) Author(names ...string) *PublicationBuilder {
+	for _, n := range names {
+		b.Contributor("author", n)
+	}
+	return b
+}
+
+// Contributor add a contributor with role, a role name or a MARC relator
+// code, the roles of the metadata (author, translator, editor, artist,
+// illustrator, letterer, penciler, colorist, inker, narrator, publisher,
+// imprint) fill their own list, the others are added as contributor with
+// the role
+func (b *PublicationBuilder) Contributor(role string, name string) *PublicationBuilder {
+	if strings.TrimSpace(name) == "" {
+		return b.errorf("empty %s name", role)
+	}
+	c := &Contributor{Name: MultiLanguage{SingleString: name}}
+	b.pub.Metadata.AddContributor(role, c)
+	return b
+}
+
+// Publisher add publishers
+func (b *PublicationBuilder) Publisher(names ...string) *PublicationBuilder {
+	for _, n := range names {
+		b.Contributor("publisher", n)
+	}
+	return b
+}
+
+// Language add the BCP 47 languages of the publication
+func (b *PublicationBuilder) Language(tags ...string) *PublicationBuilder {
+	for _, tag := range tags {
+		if _, err := language.Parse(tag); err != nil {
+			b.errorf("invalid language %q", tag)
+			continue
+		}
+		b.pub.Metadata.Language = append(b.pub.Metadata.Language, tag)
+	}
+	return b
+}
+
+// Published set the publication date
+func (b *PublicationBuilder) Published(t time.Time) *PublicationBuilder {
+	b.pub.Metadata.PublicationDate = &t
+	return b
+}
+
+// Modified set the last modification time
+func (b *PublicationBuilder) Modified(t time.Time) *PublicationBuilder {
+	b.pub.Metadata.Modified = &t
+	return b
+}
+
+// Description set the description
+func (b *PublicationBuilder) Description(s string) *PublicationBuilder {
+	b.pub.Metadata.Description = s
+	return b
+}
+
+// Subject add a subject, scheme and code are optional
+func (b *PublicationBuilder) Subject(name string, scheme string, code string) *PublicationBuilder {
+	if name == "" {
+		return b.errorf("empty subject name")
+	}
+	b.pub.Metadata.Subject = append(b.pub.Metadata.Subject, &Subject{Name: name, Scheme: scheme, Code: code})
+	return b
+}
+
+// Series add the publication to a series at position, 0 when unknown
+func (b *PublicationBuilder) Series(name string, position float64) *PublicationBuilder {
+	if name == "" {
+		return b.errorf("empty series name")
+	}
+	if position < 0 {
+		return b.errorf("negative position %v in series %q", position, name)
+	}
+	col := b.pub.BelongsToSeries(name)
+	col.Position = position
+	return b
+}
+
+// Collection add the publication to a collection
+func (b *PublicationBuilder) Collection(name string) *PublicationBuilder {
+	if name == "" {
+		return b.errorf("empty collection name")
+	}
+	b.pub.BelongsToCollection(name)
+	return b
+}
+
+// Acquisition add an acquisition link, the publication is open access when
+// it has no price or is free and sold otherwise, with one price per currency
+func (b *PublicationBuilder) Acquisition(href string, mediaType string, prices ...Price) *PublicationBuilder {
+	if err := checkHref(href, false); err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	if mediaType == "" {
+		return b.errorf("acquisition link %s has no type", href)
+	}
+
+	r := rel.AcquisitionOpenAccess
+	l := &Link{Href: href, TypeLink: mediaType}
+	if !Prices(prices).IsFree() {
+		seen := make(map[string]bool)
+		for _, price := range prices {
+			if err := price.Validate(); err != nil {
+				b.errs = append(b.errs, fmt.Errorf("%w for %s", err, href))
+				return b
+			}
+			if seen[price.Currency] {
+				return b.errorf("several prices in %s for %s", price.Currency, href)
+			}
+			seen[price.Currency] = true
+		}
+		r = rel.AcquisitionBuy
+		l.Properties = &Properties{Price: append(Prices(nil), prices...)}
+	}
+	l.Rel = []string{r}
+	b.pub.Links = append(b.pub.Links, l)
+	return b
+}
+
+// Link add a link
+func (b *PublicationBuilder) Link(href string, rel string, typeLink string) *PublicationBuilder {
+	if err := checkHref(href, false); err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	l := &Link{Href: href, TypeLink: typeLink}
+	if rel != "" {
+		l.Rel = []string{rel}
+	}
+	b.pub.Links = append(b.pub.Links, l)
+	return b
+}
+
+// Cover add the cover image
+func (b *PublicationBuilder) Cover(href string, typeLink string) *PublicationBuilder {
+	return b.Image(href, rel.Image, typeLink, 0, 0)
+}
+
+// Thumbnail add the thumbnail image
+func (b *PublicationBuilder) Thumbnail(href string, typeLink string) *PublicationBuilder {
+	return b.Image(href, rel.Thumbnail, typeLink, 0, 0)
+}
+
+// Image add an image, width and height are optional
+func (b *PublicationBuilder) Image(href string, rel string, typeLink string, width int, height int) *PublicationBuilder {
+	if err := checkHref(href, false); err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	if typeLink != "" && !mediatype.IsImage(typeLink) {
+		return b.errorf("image %s has type %s", href, typeLink)
+	}
+	l := &Link{Href: href, TypeLink: typeLink, Width: width, Height: height}
+	if rel != "" {
+		l.Rel = []string{rel}
+	}
+	b.pub.Images = append(b.pub.Images, l)
+	return b
+}
+
+// Build return the publication, or the errors found while building it, a
+// publication needs a title and an acquisition link, the contributors
+// without SortAs get a generated one and the @type default to
+// http://schema.org/EBook
+func (b *PublicationBuilder) Build() (Publication, error) {
+	errs := b.errs
+	if b.pub.Metadata.Title.IsEmpty() {
+		errs = append(errs, errors.New("opds2: publication has no title"))
+	}
+	acquisition := false
+	for _, l := range b.pub.Links {
+		acquisition = acquisition || l.IsAcquisition()
+	}
+	if !acquisition {
+		errs = append(errs, errors.New("opds2: publication has no acquisition link"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Publication{}, err
+	}
+	b.pub.Metadata.GenerateSortAs()
+	if b.pub.Metadata.RDFType == "" {
+		b.pub.Metadata.RDFType = "http://schema.org/EBook"
+	}
+	return b.pub, nil
+}
+
+func checkHref(href string, templated bool) error {
+	if href == "" {
+		return errors.New("opds2: empty href")
+	}
+	if templated {
+		return nil
+	}
+	if _, err := url.Parse(href); err != nil {
+		return fmt.Errorf("opds2: invalid href %q: %w", href, err)
+	}
+	return nil
+}
@@ -16,6 +16,8 @@ type Collections []*Collection
 // Collection construct used for collection/serie metadata
 type Collection struct {
 	*Contributor
+	// Position is the position of the publication in the collection, it
+	// shadows the position of the contributor
 	Position float64 `json:"position,omitempty"`
 }
 
@@ -23,10 +25,18 @@ func NewCollection(col any) Collections {
 	return parseCollections(col)
 }
 
+// String return the name of the collection, empty when it has none
+func (c *Collection) String() string {
+	if c == nil || c.Contributor == nil {
+		return ""
+	}
+	return c.Name.String()
+}
+
 func (c Collections) StringSlice() []string {
 	var cols []string
 	for _, col := range c {
-		cols = append(cols, col.Name.SingleString)
+		cols = append(cols, col.String())
 	}
 	return cols
 }
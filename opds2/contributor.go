@@ -1,6 +1,10 @@
 package opds2
 
-import "strings"
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
 
 // Contributor Slice
 type Contributors []*Contributor
@@ -10,14 +14,47 @@ type Contributor struct {
 	Name       MultiLanguage `json:"name,omitempty"`
 	SortAs     string        `json:"sort_as,omitempty"`
 	Identifier string        `json:"identifier,omitempty"`
-	Role       string        `json:"role,omitempty"`
-	Links      Links         `json:"links,omitempty"`
+	// Role are the roles of the contributors of Contributor, role names or
+	// MARC relator codes
+	Role StringOrArray `json:"role,omitempty"`
+	// Position is the position of the publication in a collection
+	Position float64 `json:"position,omitempty"`
+	Links    Links   `json:"links,omitempty"`
 }
 
 func NewContributor(con any) Contributors {
 	return parseContributors(con)
 }
 
+// HasRole check if the contributor has role, a role name or a MARC relator
+// code
+func (c *Contributor) HasRole(role string) bool {
+	role = NormalizeRole(role)
+	for _, r := range c.Role {
+		if NormalizeRole(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// AddRole add role to the roles of the contributor unless it already has it
+func (c *Contributor) AddRole(role string) {
+	if role == "" || c.HasRole(role) {
+		return
+	}
+	c.Role = append(c.Role, role)
+}
+
+// SortKey return the string used to sort the contributor, its SortAs or
+// the sort name of the name in lang
+func (c *Contributor) SortKey(lang string) string {
+	if c.SortAs != "" {
+		return c.SortAs
+	}
+	return SortName(c.Name.String(), lang)
+}
+
 func (c Contributors) StringSlice() []string {
 	var cons []string
 	for _, con := range c {
@@ -26,15 +63,51 @@ func (c Contributors) StringSlice() []string {
 	return cons
 }
 
+// String return every name of the contributors like "A, B & C"
 func (c Contributors) String() string {
-	var sep string
-	if len(c) > 0 {
-		switch c[0].Role {
-		case "publisher", "imprint", "contributor":
-			sep = ", "
-		default:
-			sep = " & "
+	return Byline{Separator: ", ", LastSeparator: " & "}.Format(c)
+}
+
+// Byline return the names of the contributors formatted with
+// DefaultByline, "A", "A & B", "A, B & C" or "A et al."
+func (c Contributors) Byline() string {
+	return DefaultByline.Format(c)
+}
+
+// Byline format the names of contributors in a single line
+type Byline struct {
+	// Separator is written between the names, ", "
+	Separator string
+	// LastSeparator is written before the last name, " & " or " and "
+	LastSeparator string
+	// Max is the number of names written, more names give the first one
+	// followed by EtAl, 0 for no limit
+	Max  int
+	EtAl string
+	// Lang are the languages the names are written in
+	Lang []language.Tag
+}
+
+// DefaultByline write up to 3 names, "A, B & C", and "A et al." for more
+var DefaultByline = Byline{Separator: ", ", LastSeparator: " & ", Max: 3, EtAl: " et al."}
+
+// Format return the byline of the contributors, contributors without name
+// are left out
+func (b Byline) Format(cons Contributors) string {
+	var names []string
+	for _, c := range cons {
+		if n := c.Name.Get(b.Lang...); n != "" {
+			names = append(names, n)
 		}
 	}
-	return strings.Join(c.StringSlice(), sep)
+	switch {
+	case len(names) == 0:
+		return ""
+	case b.Max > 0 && len(names) > b.Max:
+		return names[0] + b.EtAl
+	case len(names) == 1:
+		return names[0]
+	}
+	last := len(names) - 1
+	return strings.Join(names[:last], b.Separator) + b.LastSeparator + names[last]
 }
@@ -1 +1,437 @@
 package opds2
+
+import (
+	"time"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/opds1"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// FromOPDS1 convert an OPDS 1.x feed to an OPDS 2.0 feed, entries with an
+// acquisition link become publications, the others navigation links, the
+// hrefs are resolved against xml:base and the URL of the feed
+func FromOPDS1(feed *opds1.Feed) Feed {
+	var opds2feed Feed
+
+	feed = resolvedOPDS1(feed)
+	opds2feed.BaseURL = feed.URL
+
+	// If acquisition link check if rel='collection' than mean it is a group, if there no rel it is a publication
+
+	opds2feed.Metadata.Title.SingleString = feed.Title
+	updated := feed.Updated
+	opds2feed.Metadata.Modified = &updated
+	if feed.TotalResults != 0 {
+		opds2feed.Metadata.NumberOfItems = feed.TotalResults
+	}
+	if feed.ItemsPerPage != 0 {
+		opds2feed.Metadata.ItemsPerPage = feed.ItemsPerPage
+	}
+
+	for _, entry := range feed.Entries {
+		// Get all entry, if entry has a acquisition puts in publication else it is a navigation link put in in links objetcs to
+		isAnNavigation := true
+		collLink := &Link{}
+
+		for _, l := range entry.Links {
+			if rel.IsAcquisition(l.Rel) {
+				isAnNavigation = false
+			}
+			if l.Rel == rel.Collection || l.Rel == rel.Group {
+				collLink.Rel = []string{rel.Collection}
+				collLink.Href = l.Href
+				collLink.Title = l.Title
+			}
+		}
+
+		if !isAnNavigation {
+			p := publicationFromEntry(entry)
+			if collLink.Href != "" {
+				opds2feed.AddPublicationInGroup(p, collLink)
+			} else {
+				opds2feed.Publications = append(opds2feed.Publications, p)
+			}
+		} else if len(entry.Links) > 0 {
+			linkNav := &Link{}
+			linkNav.Title = entry.Title
+			linkNav.Rel = []string{entry.Links[0].Rel}
+			linkNav.TypeLink = entry.Links[0].TypeLink
+			linkNav.Href = entry.Links[0].Href
+
+			if collLink.Href != "" {
+				opds2feed.AddNavigationInGroup(linkNav, collLink)
+			} else {
+				opds2feed.Navigation = append(opds2feed.Navigation, linkNav)
+			}
+		}
+	}
+
+	for _, l := range feed.Links {
+		linkFeed := linkFromOPDS1(l)
+
+		if l.Rel == rel.Facet {
+			linkFeed.Properties = &Properties{NumberOfItems: l.Count}
+			opds2feed.AddFacet(linkFeed, l.FacetGroup)
+		} else {
+			opds2feed.Links = append(opds2feed.Links, linkFeed)
+		}
+	}
+
+	return opds2feed
+}
+
+// resolvedOPDS1 return a copy of feed with the hrefs resolved
+func resolvedOPDS1(feed *opds1.Feed) *opds1.Feed {
+	c := *feed
+	c.Links = append([]opds1.Link(nil), feed.Links...)
+	c.Entries = make([]opds1.Entry, len(feed.Entries))
+	for i, e := range feed.Entries {
+		e.Links = append([]opds1.Link(nil), e.Links...)
+		c.Entries[i] = e
+	}
+	c.ResolveLinks()
+	return &c
+}
+
+func publicationFromEntry(entry opds1.Entry) Publication {
+	p := Publication{}
+	p.Metadata.Title.SingleString = entry.Title
+	if entry.Identifier != "" {
+		p.Metadata.Identifier = entry.Identifier
+		p.Metadata.AddAltIdentifier(entry.ID)
+	} else {
+		p.Metadata.Identifier = entry.ID
+	}
+	if entry.Language != "" {
+		p.Metadata.Language = []string{entry.Language}
+	}
+	p.Metadata.Modified = entry.Updated
+	p.Metadata.PublicationDate = entry.Published
+	p.Metadata.Rights = entry.Rights
+	for _, s := range entry.Series {
+		coll := &Collection{Contributor: &Contributor{}}
+		coll.Name.SingleString = s.Name
+		coll.Position = float64(s.Position)
+		if s.URL != "" {
+			coll.Links = append(coll.Links, &Link{Href: s.URL})
+		}
+		if p.Metadata.BelongsTo == nil {
+			p.Metadata.BelongsTo = &BelongsTo{}
+		}
+		p.Metadata.BelongsTo.Series = append(p.Metadata.BelongsTo.Series, coll)
+	}
+	if entry.Publisher != "" {
+		c := &Contributor{}
+		c.Name.SingleString = entry.Publisher
+		p.Metadata.Publisher = append(p.Metadata.Publisher, c)
+	}
+
+	for _, cat := range entry.Category {
+		s := &Subject{Code: cat.Term, Name: cat.Label, Scheme: NormalizeSubjectScheme(cat.Scheme)}
+		if s.Name == "" {
+			s.Name = s.Label()
+		}
+		p.Metadata.Subject = append(p.Metadata.Subject, s)
+	}
+
+	for _, aut := range entry.Author {
+		cont := &Contributor{}
+		cont.Name.SingleString = aut.Name
+		cont.Identifier = aut.URI
+		p.Metadata.Author = append(p.Metadata.Author, cont)
+	}
+
+	// for html resource like description, atom:summary go to description
+	// if atom:content use it in description else use summary
+	if entry.Content.Content != "" {
+		p.Metadata.Description = entry.Content.Content
+	} else if entry.Summary.Content != "" {
+		p.Metadata.Description = entry.Summary.Content
+	}
+
+	for _, link := range entry.Links {
+		l := linkFromOPDS1(link)
+
+		if len(link.IndirectAcquisition) > 0 {
+			if l.Properties == nil {
+				l.Properties = &Properties{}
+			}
+			for _, ia := range link.IndirectAcquisition {
+				l.Properties.IndirectAcquisition = append(l.Properties.IndirectAcquisition, indirectFromOPDS1(ia))
+			}
+		}
+
+		for _, price := range link.Price {
+			value, err := ParseDecimal(price.Value)
+			if price.CurrencyCode == "" || err != nil {
+				continue
+			}
+			if l.Properties == nil {
+				l.Properties = &Properties{}
+			}
+			l.Properties.Price = append(l.Properties.Price, Price{Currency: price.CurrencyCode, Value: value})
+		}
+
+		if p := lendingFromOPDS1(link); p != nil {
+			if l.Properties == nil {
+				l.Properties = &Properties{}
+			}
+			l.Properties.Availability, l.Properties.Holds, l.Properties.Copies = p.Availability, p.Holds, p.Copies
+		}
+
+		if link.Rel == rel.Collection || link.Rel == rel.Group {
+		} else if link.Rel == rel.Image || link.Rel == rel.Thumbnail {
+			p.Images = append(p.Images, l)
+		} else {
+			p.Links = append(p.Links, l)
+		}
+	}
+
+	return p
+}
+
+func linkFromOPDS1(link opds1.Link) *Link {
+	l := &Link{}
+	l.Href = link.Href
+	l.TypeLink = link.TypeLink
+	if link.Rel != "" {
+		l.Rel = []string{link.Rel}
+	}
+	l.Title = link.Title
+	return l
+}
+
+// lendingFromOPDS1 return the availability, holds and copies of a link,
+// nil when it has none
+func lendingFromOPDS1(link opds1.Link) *Properties {
+	if link.Availability == nil && link.Holds == nil && link.Copies == nil {
+		return nil
+	}
+	p := &Properties{}
+	if a := link.Availability; a != nil {
+		p.Availability = &Availability{State: a.Status, Since: parseOptionalDate(a.Since), Until: parseOptionalDate(a.Until)}
+	}
+	if h := link.Holds; h != nil {
+		p.Holds = &Holds{Total: h.Total, Position: h.Position}
+	}
+	if c := link.Copies; c != nil {
+		p.Copies = &Copies{Total: c.Total, Available: c.Available}
+	}
+	return p
+}
+
+func indirectFromOPDS1(ia opds1.IndirectAcquisition) IndirectAcquisition {
+	ind := IndirectAcquisition{}
+	ind.TypeAcquisition = ia.TypeAcquisition
+	for _, iac := range ia.IndirectAcquisition {
+		ind.Child = append(ind.Child, indirectFromOPDS1(iac))
+	}
+	return ind
+}
+
+// ToOPDS1 convert the feed to an OPDS 1.x Atom feed, id is used as the
+// atom:id of the feed. Publications become acquisition entries, navigation
+// links navigation entries and facets facet links.
+func (feed *Feed) ToOPDS1(id string) opds1.Feed {
+	var f opds1.Feed
+
+	f.ID = id
+	f.Title = feed.Metadata.Title.String()
+	if feed.Metadata.Modified != nil {
+		f.Updated = *feed.Metadata.Modified
+	} else {
+		f.Updated = time.Now()
+	}
+	f.TotalResults = feed.Metadata.NumberOfItems
+	f.ItemsPerPage = feed.Metadata.ItemsPerPage
+
+	kind := "navigation"
+	if len(feed.Publications) > 0 {
+		kind = "acquisition"
+	}
+	for _, g := range feed.Groups {
+		if len(g.Publications) > 0 {
+			kind = "acquisition"
+		}
+	}
+
+	for _, l := range feed.Links {
+		f.Links = append(f.Links, linkToOPDS1(l, kind))
+	}
+
+	for _, facet := range feed.Facets {
+		for _, l := range facet.Links {
+			fl := linkToOPDS1(l, kind)
+			fl.Rel = rel.Facet
+			fl.FacetGroup = facet.Metadata.Title.String()
+			if l.Properties != nil {
+				fl.Count = l.Properties.NumberOfItems
+			}
+			for _, r := range l.Rel {
+				if r == rel.Self {
+					fl.ActiveFacet = true
+				}
+			}
+			f.Links = append(f.Links, fl)
+		}
+	}
+
+	for _, n := range feed.Navigation {
+		f.Entries = append(f.Entries, navigationToEntry(n, f.Updated, kind, nil))
+	}
+
+	for _, p := range feed.Publications {
+		f.Entries = append(f.Entries, publicationToEntry(p, kind, nil))
+	}
+
+	for _, g := range feed.Groups {
+		var group *opds1.Link
+		if len(g.Links) > 0 {
+			group = &opds1.Link{
+				Rel:   rel.Collection,
+				Href:  g.Links[0].Href,
+				Title: g.Metadata.Title.String(),
+			}
+		}
+		for _, n := range g.Navigation {
+			f.Entries = append(f.Entries, navigationToEntry(n, f.Updated, kind, group))
+		}
+		for _, p := range g.Publications {
+			f.Entries = append(f.Entries, publicationToEntry(p, kind, group))
+		}
+	}
+
+	return f
+}
+
+func navigationToEntry(n *Link, updated time.Time, kind string, group *opds1.Link) opds1.Entry {
+	e := opds1.Entry{}
+	e.Title = n.Title
+	e.ID = n.Href
+	e.Updated = &updated
+	l := linkToOPDS1(n, kind)
+	if l.Rel == "" {
+		l.Rel = rel.Subsection
+	}
+	e.Links = append(e.Links, l)
+	if group != nil {
+		e.Links = append(e.Links, *group)
+	}
+	return e
+}
+
+func publicationToEntry(p Publication, kind string, group *opds1.Link) opds1.Entry {
+	e := opds1.Entry{}
+	m := p.Metadata
+
+	e.Title = m.Title.String()
+	e.ID = m.Identifier
+	e.Identifier = m.Identifier
+	if m.Modified != nil {
+		e.Updated = m.Modified
+	} else {
+		t := time.Now()
+		e.Updated = &t
+	}
+	e.Published = m.PublicationDate
+	e.Rights = m.Rights
+	if len(m.Language) > 0 {
+		e.Language = m.Language[0]
+	}
+	if len(m.Publisher) > 0 {
+		e.Publisher = m.Publisher[0].Name.String()
+	}
+	for _, a := range m.Author {
+		e.Author = append(e.Author, opds1.Author{Name: a.Name.String(), URI: a.Identifier})
+	}
+	for _, s := range m.Subject {
+		e.Category = append(e.Category, opds1.Category{Scheme: s.Scheme, Term: subjectTerm(s), Label: s.Label()})
+	}
+	if m.Description != "" {
+		e.Summary = opds1.Content{Content: m.Description, ContentType: "text"}
+	}
+	if m.BelongsTo != nil {
+		for _, s := range m.BelongsTo.Series {
+			serie := opds1.Serie{Position: float32(s.Position)}
+			if s.Contributor != nil {
+				serie.Name = s.Name.String()
+				if len(s.Links) > 0 {
+					serie.URL = s.Links[0].Href
+				}
+			}
+			e.Series = append(e.Series, serie)
+		}
+	}
+
+	for _, l := range p.Links {
+		e.Links = append(e.Links, linkToOPDS1(l, kind))
+	}
+	for _, l := range p.Images {
+		e.Links = append(e.Links, linkToOPDS1(l, kind))
+	}
+	if group != nil {
+		e.Links = append(e.Links, *group)
+	}
+
+	return e
+}
+
+func subjectTerm(s *Subject) string {
+	if s.Code != "" {
+		return s.Code
+	}
+	return s.Name
+}
+
+func linkToOPDS1(l *Link, kind string) opds1.Link {
+	link := opds1.Link{}
+	link.Href = l.Href
+	link.Title = l.Title
+	link.TypeLink = l.TypeLink
+	if mediatype.Matches(mediatype.OPDS2, l.TypeLink) {
+		link.TypeLink = mediatype.OPDS1 + ";kind=" + kind
+	}
+	if len(l.Rel) > 0 {
+		link.Rel = l.Rel[0]
+	}
+	if l.Properties != nil {
+		for _, price := range l.Properties.Price {
+			link.Price = append(link.Price, opds1.Price{CurrencyCode: price.Currency, Value: price.Value.String()})
+		}
+		for _, ia := range l.Properties.IndirectAcquisition {
+			link.IndirectAcquisition = append(link.IndirectAcquisition, indirectToOPDS1(ia))
+		}
+		if a := l.Properties.Availability; a != nil {
+			link.Availability = &opds1.Availability{Status: a.State, Since: formatOptionalDate(a.Since), Until: formatOptionalDate(a.Until)}
+		}
+		if h := l.Properties.Holds; h != nil {
+			link.Holds = &opds1.Holds{Total: h.Total, Position: h.Position}
+		}
+		if c := l.Properties.Copies; c != nil {
+			link.Copies = &opds1.Copies{Total: c.Total, Available: c.Available}
+		}
+	}
+	return link
+}
+
+func formatOptionalDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func indirectToOPDS1(ia IndirectAcquisition) opds1.IndirectAcquisition {
+	ind := opds1.IndirectAcquisition{}
+	ind.TypeAcquisition = ia.TypeAcquisition
+	for _, c := range ia.Child {
+		ind.IndirectAcquisition = append(ind.IndirectAcquisition, indirectToOPDS1(c))
+	}
+	return ind
+}
+
+// ToOPDS1 convert the publication to an OPDS 1.x acquisition entry
+func (publication *Publication) ToOPDS1() opds1.Entry {
+	return publicationToEntry(*publication, "acquisition", nil)
+}
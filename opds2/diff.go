@@ -0,0 +1,428 @@
+package opds2
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FeedDiff list the changes between two snapshots of a feed
+type FeedDiff struct {
+	Metadata   []Change          `json:"metadata,omitempty"`
+	Added      []Publication     `json:"added,omitempty"`
+	Removed    []Publication     `json:"removed,omitempty"`
+	Modified   []PublicationDiff `json:"modified,omitempty"`
+	Navigation LinksDiff         `json:"navigation,omitempty"`
+	Facets     LinksDiff         `json:"facets,omitempty"`
+}
+
+// PublicationDiff is the field level changes of a publication found in
+// both feeds
+type PublicationDiff struct {
+	Key     string   `json:"key"`
+	Title   string   `json:"title"`
+	Changes []Change `json:"changes"`
+}
+
+// Change is the old and new value of a field
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// LinksDiff list the links added, removed or changed, links are matched by
+// href
+type LinksDiff struct {
+	Added   Links    `json:"added,omitempty"`
+	Removed Links    `json:"removed,omitempty"`
+	Changed []Change `json:"changed,omitempty"`
+}
+
+// Diff compare two feeds, publications of the top level and of the groups
+// are matched by identifier, falling back to their acquisition href
+func Diff(old, new *Feed) FeedDiff {
+	var d FeedDiff
+
+	d.Metadata = feedMetadataChanges(old.Metadata, new.Metadata)
+
+	oldPubs, oldKeys := indexPublications(old)
+	newPubs, newKeys := indexPublications(new)
+	for _, k := range newKeys {
+		if _, ok := oldPubs[k]; !ok {
+			d.Added = append(d.Added, *newPubs[k])
+		}
+	}
+	for _, k := range oldKeys {
+		np, ok := newPubs[k]
+		if !ok {
+			d.Removed = append(d.Removed, *oldPubs[k])
+			continue
+		}
+		if changes := publicationChanges(oldPubs[k], np); len(changes) > 0 {
+			d.Modified = append(d.Modified, PublicationDiff{Key: k, Title: np.Metadata.Title.String(), Changes: changes})
+		}
+	}
+
+	d.Navigation = diffLinks(navigationLinks(old), navigationLinks(new), hrefKey)
+	d.Facets = diffLinks(facetLinks(old), facetLinks(new), facetKey)
+
+	return d
+}
+
+// IsEmpty check if the feeds were identical
+func (d FeedDiff) IsEmpty() bool {
+	return len(d.Metadata) == 0 && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0 &&
+		d.Navigation.IsEmpty() && d.Facets.IsEmpty()
+}
+
+// IsEmpty check if no link changed
+func (d LinksDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String format the diff for humans, one change per line
+func (d FeedDiff) String() string {
+	var b strings.Builder
+	for _, c := range d.Metadata {
+		fmt.Fprintf(&b, "~ feed %s\n", c)
+	}
+	for _, p := range d.Added {
+		fmt.Fprintf(&b, "+ %s (%s)\n", p.Metadata.Title.String(), PublicationKey(&p))
+	}
+	for _, p := range d.Removed {
+		fmt.Fprintf(&b, "- %s (%s)\n", p.Metadata.Title.String(), PublicationKey(&p))
+	}
+	for _, p := range d.Modified {
+		fmt.Fprintf(&b, "~ %s (%s)\n", p.Title, p.Key)
+		for _, c := range p.Changes {
+			fmt.Fprintf(&b, "    %s\n", c)
+		}
+	}
+	writeLinksDiff(&b, "navigation", d.Navigation)
+	writeLinksDiff(&b, "facet", d.Facets)
+	return b.String()
+}
+
+// String format the change as "field: old -> new"
+func (c Change) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("%s: added %s", c.Field, c.New)
+	case c.New == "":
+		return fmt.Sprintf("%s: removed %s", c.Field, c.Old)
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+}
+
+func writeLinksDiff(b *strings.Builder, name string, d LinksDiff) {
+	for _, l := range d.Added {
+		fmt.Fprintf(b, "+ %s %s <%s>\n", name, l.Title, l.Href)
+	}
+	for _, l := range d.Removed {
+		fmt.Fprintf(b, "- %s %s <%s>\n", name, l.Title, l.Href)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(b, "~ %s %s\n", name, c)
+	}
+}
+
+// PublicationKey return the key used to match publications between feeds:
+// the identifier or the href of the first acquisition link
+func PublicationKey(p *Publication) string {
+	if p.Metadata.Identifier != "" {
+		return p.Metadata.Identifier
+	}
+	for _, l := range p.Links {
+		if l.IsAcquisition() {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+func indexPublications(feed *Feed) (map[string]*Publication, []string) {
+	index := make(map[string]*Publication)
+	var keys []string
+	add := func(pubs []Publication) {
+		for i := range pubs {
+			k := PublicationKey(&pubs[i])
+			if _, ok := index[k]; ok || k == "" {
+				continue
+			}
+			index[k] = &pubs[i]
+			keys = append(keys, k)
+		}
+	}
+	add(feed.Publications)
+	for _, g := range feed.Groups {
+		add(g.Publications)
+	}
+	return index, keys
+}
+
+func feedMetadataChanges(a, b Metadata) []Change {
+	var changes []Change
+	compare(&changes, "title", multiLanguageString(a.Title), multiLanguageString(b.Title))
+	compare(&changes, "@type", a.RDFType, b.RDFType)
+	compare(&changes, "numberOfItems", intString(a.NumberOfItems), intString(b.NumberOfItems))
+	return changes
+}
+
+func publicationChanges(a, b *Publication) []Change {
+	var changes []Change
+	ma, mb := &a.Metadata, &b.Metadata
+
+	compare(&changes, "title", multiLanguageString(ma.Title), multiLanguageString(mb.Title))
+	compare(&changes, "identifier", ma.Identifier, mb.Identifier)
+	compare(&changes, "@type", ma.RDFType, mb.RDFType)
+	for _, c := range []struct {
+		field string
+		old   Contributors
+		new   Contributors
+	}{
+		{"author", ma.Author, mb.Author},
+		{"translator", ma.Translator, mb.Translator},
+		{"editor", ma.Editor, mb.Editor},
+		{"artist", ma.Artist, mb.Artist},
+		{"illustrator", ma.Illustrator, mb.Illustrator},
+		{"letterer", ma.Letterer, mb.Letterer},
+		{"penciler", ma.Penciler, mb.Penciler},
+		{"colorist", ma.Colorist, mb.Colorist},
+		{"inker", ma.Inker, mb.Inker},
+		{"narrator", ma.Narrator, mb.Narrator},
+		{"contributor", ma.Contributor, mb.Contributor},
+		{"publisher", ma.Publisher, mb.Publisher},
+		{"imprint", ma.Imprint, mb.Imprint},
+	} {
+		compare(&changes, c.field, strings.Join(c.old.StringSlice(), "; "), strings.Join(c.new.StringSlice(), "; "))
+	}
+	compare(&changes, "language", strings.Join(ma.Language, ", "), strings.Join(mb.Language, ", "))
+	compare(&changes, "modified", timeString(ma.Modified), timeString(mb.Modified))
+	compare(&changes, "published", timeString(ma.PublicationDate), timeString(mb.PublicationDate))
+	compare(&changes, "description", ma.Description, mb.Description)
+	compare(&changes, "source", ma.Source, mb.Source)
+	compare(&changes, "rights", ma.Rights, mb.Rights)
+	compare(&changes, "subject", ma.Subject.String(), mb.Subject.String())
+	compare(&changes, "series", belongsToString(ma.BelongsTo, true), belongsToString(mb.BelongsTo, true))
+	compare(&changes, "collection", belongsToString(ma.BelongsTo, false), belongsToString(mb.BelongsTo, false))
+	compare(&changes, "duration", intString(ma.Duration), intString(mb.Duration))
+
+	changes = append(changes, linkChanges(a.Links, b.Links)...)
+	images := diffLinks(a.Images, b.Images, hrefKey)
+	for _, l := range images.Added {
+		changes = append(changes, Change{Field: "image", New: l.Href})
+	}
+	for _, l := range images.Removed {
+		changes = append(changes, Change{Field: "image", Old: l.Href})
+	}
+
+	return changes
+}
+
+// linkChanges compare the links of a publication by rel and media type,
+// reporting new and removed formats, moved hrefs and price changes
+func linkChanges(a, b Links) []Change {
+	var changes []Change
+	oldLinks, oldKeys := indexLinks(a)
+	newLinks, newKeys := indexLinks(b)
+
+	for _, k := range newKeys {
+		if _, ok := oldLinks[k]; !ok {
+			changes = append(changes, Change{Field: "link " + k, New: newLinks[k].Href})
+		}
+	}
+	for _, k := range oldKeys {
+		ol := oldLinks[k]
+		nl, ok := newLinks[k]
+		if !ok {
+			changes = append(changes, Change{Field: "link " + k, Old: ol.Href})
+			continue
+		}
+		compare(&changes, "href "+k, ol.Href, nl.Href)
+		compare(&changes, "price "+k, priceString(ol), priceString(nl))
+		compare(&changes, "indirectAcquisition "+k, indirectString(ol), indirectString(nl))
+	}
+	return changes
+}
+
+func indexLinks(links Links) (map[string]*Link, []string) {
+	index := make(map[string]*Link)
+	var keys []string
+	for _, l := range links {
+		k := strings.TrimSpace(strings.Join(l.Rel, " ") + " " + l.TypeLink)
+		if _, ok := index[k]; ok {
+			// the same format sold in another currency
+			k += " " + priceCurrency(l)
+			if _, ok := index[k]; ok {
+				continue
+			}
+		}
+		index[k] = l
+		keys = append(keys, k)
+	}
+	return index, keys
+}
+
+// diffLinks compare links matched by key
+func diffLinks(a, b Links, key func(*Link) string) LinksDiff {
+	var d LinksDiff
+	oldLinks := make(map[string]*Link)
+	for _, l := range a {
+		oldLinks[key(l)] = l
+	}
+	newLinks := make(map[string]*Link)
+	for _, l := range b {
+		k := key(l)
+		newLinks[k] = l
+		ol, ok := oldLinks[k]
+		if !ok {
+			d.Added = append(d.Added, l)
+			continue
+		}
+		compare(&d.Changed, k+" title", ol.Title, l.Title)
+		compare(&d.Changed, k+" numberOfItems", intString(numberOfItems(ol)), intString(numberOfItems(l)))
+	}
+	for _, l := range a {
+		if _, ok := newLinks[key(l)]; !ok {
+			d.Removed = append(d.Removed, l)
+		}
+	}
+	return d
+}
+
+func hrefKey(l *Link) string {
+	return l.Href
+}
+
+// facetKey match facets by group and href, facetLinks prefix the title
+// with the group
+func facetKey(l *Link) string {
+	group, _, _ := strings.Cut(l.Title, ": ")
+	return group + " " + l.Href
+}
+
+func navigationLinks(feed *Feed) Links {
+	links := append(Links(nil), feed.Navigation...)
+	for _, g := range feed.Groups {
+		links = append(links, g.Navigation...)
+	}
+	return links
+}
+
+// facetLinks return copies of the facet links titled "group: title"
+func facetLinks(feed *Feed) Links {
+	var links Links
+	for _, f := range feed.Facets {
+		for _, l := range f.Links {
+			c := *l
+			c.Title = f.Metadata.Title.String() + ": " + l.Title
+			links = append(links, &c)
+		}
+	}
+	return links
+}
+
+func compare(changes *[]Change, field string, old string, new string) {
+	if old != new {
+		*changes = append(*changes, Change{Field: field, Old: old, New: new})
+	}
+}
+
+func multiLanguageString(m MultiLanguage) string {
+	if len(m.MultiString) == 0 {
+		return m.SingleString
+	}
+	langs := make([]string, 0, len(m.MultiString))
+	for l := range m.MultiString {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	var parts []string
+	for _, l := range langs {
+		parts = append(parts, l+": "+m.MultiString[l])
+	}
+	return strings.Join(parts, "; ")
+}
+
+func belongsToString(b *BelongsTo, series bool) string {
+	if b == nil {
+		return ""
+	}
+	cols := b.Collection
+	if series {
+		cols = b.Series
+	}
+	var parts []string
+	for _, c := range cols {
+		s := ""
+		if c.Contributor != nil {
+			s = c.Name.String()
+		}
+		if c.Position != 0 {
+			s += " #" + strconv.FormatFloat(c.Position, 'f', -1, 64)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func timeString(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func intString(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return strconv.Itoa(i)
+}
+
+func priceString(l *Link) string {
+	if l.Properties == nil {
+		return ""
+	}
+	var prices []string
+	for _, p := range l.Properties.Price {
+		prices = append(prices, p.Value.String()+" "+p.Currency)
+	}
+	sort.Strings(prices)
+	return strings.Join(prices, ", ")
+}
+
+func priceCurrency(l *Link) string {
+	if l.Properties == nil {
+		return ""
+	}
+	currencies := l.Properties.Price.Currencies()
+	sort.Strings(currencies)
+	return strings.Join(currencies, " ")
+}
+
+func numberOfItems(l *Link) int {
+	if l.Properties == nil {
+		return 0
+	}
+	return l.Properties.NumberOfItems
+}
+
+func indirectString(l *Link) string {
+	if l.Properties == nil {
+		return ""
+	}
+	var parts []string
+	var walk func(prefix string, ias []IndirectAcquisition)
+	walk = func(prefix string, ias []IndirectAcquisition) {
+		for _, ia := range ias {
+			parts = append(parts, prefix+ia.TypeAcquisition)
+			walk(prefix+ia.TypeAcquisition+" > ", ia.Child)
+		}
+	}
+	walk("", l.Properties.IndirectAcquisition)
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,412 @@
+package opds2
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// Facet dimensions computed by a FacetEngine, they are also the query
+// parameters of the facet links
+const (
+	FacetLanguage     = "language"
+	FacetFormat       = "format"
+	FacetSubject      = "subject"
+	FacetAuthor       = "author"
+	FacetAvailability = "availability"
+	FacetPrice        = "price"
+)
+
+// PriceRange is a value of the price facet, in the currency of the
+// engine, Max is exclusive and 0 when the range has no upper bound, the
+// "free" range match the publications costing nothing
+type PriceRange struct {
+	Value string
+	Title string
+	Min   float64
+	Max   float64
+}
+
+// FacetSelection is the value selected for each facet dimension
+type FacetSelection map[string]string
+
+// FacetEngine compute the facet groups of a set of publications and filter
+// them by the selected facets
+type FacetEngine struct {
+	// BaseURL of the facet links, the selection is added as query
+	// parameters
+	BaseURL string
+	// Kinds are the facet dimensions computed, in order
+	Kinds []string
+	// Titles of the facet groups by kind
+	Titles map[string]string
+	// PriceRanges are the values of the price facet
+	PriceRanges []PriceRange
+	// Currency of the price ranges, the prices in other currencies are
+	// ignored
+	Currency string
+	// MaxValues limit the number of links of the author and subject
+	// facets, the most frequent values are kept
+	MaxValues int
+}
+
+// NewFacetEngine create an engine computing every facet dimension with
+// links to baseURL
+func NewFacetEngine(baseURL string) *FacetEngine {
+	return &FacetEngine{
+		BaseURL: baseURL,
+		Kinds:   []string{FacetLanguage, FacetFormat, FacetSubject, FacetAuthor, FacetAvailability, FacetPrice},
+		Titles: map[string]string{
+			FacetLanguage:     "Language",
+			FacetFormat:       "Format",
+			FacetSubject:      "Subject",
+			FacetAuthor:       "Author",
+			FacetAvailability: "Availability",
+			FacetPrice:        "Price",
+		},
+		PriceRanges: []PriceRange{
+			{Value: "free", Title: "Free"},
+			{Value: "0-5", Title: "Under 5", Min: 0.01, Max: 5},
+			{Value: "5-10", Title: "5 to 10", Min: 5, Max: 10},
+			{Value: "10-20", Title: "10 to 20", Min: 10, Max: 20},
+			{Value: "20-", Title: "20 and more", Min: 20},
+		},
+		Currency:  "USD",
+		MaxValues: 20,
+	}
+}
+
+// ParseFacetSelection read the selected facets from the query of a facet
+// link
+func ParseFacetSelection(query url.Values) FacetSelection {
+	sel := make(FacetSelection)
+	for _, kind := range []string{FacetLanguage, FacetFormat, FacetSubject, FacetAuthor, FacetAvailability, FacetPrice} {
+		if v := query.Get(kind); v != "" {
+			sel[kind] = v
+		}
+	}
+	return sel
+}
+
+// Apply filter the publications of the feed by the selection and replace
+// its facets with the computed facet groups
+func (e *FacetEngine) Apply(feed *Feed, sel FacetSelection) {
+	all := feed.Publications
+	feed.Publications = e.Filter(all, sel)
+	feed.Facets = e.Facets(all, sel)
+	feed.Metadata.NumberOfItems = len(feed.Publications)
+}
+
+// Filter return the publications matching every selected facet
+func (e *FacetEngine) Filter(pubs []Publication, sel FacetSelection) []Publication {
+	var res []Publication
+	for i := range pubs {
+		if e.match(&pubs[i], sel, "") {
+			res = append(res, pubs[i])
+		}
+	}
+	return res
+}
+
+// Facets compute a facet group per kind, the count of a facet is the
+// number of publications it would return keeping the selection of the
+// other groups, the selected facet (or "All") has the self rel
+func (e *FacetEngine) Facets(pubs []Publication, sel FacetSelection) []Facet {
+	var facets []Facet
+	for _, kind := range e.Kinds {
+		counts := make(map[string]int)
+		titles := make(map[string]string)
+		total := 0
+		for i := range pubs {
+			p := &pubs[i]
+			if !e.match(p, sel, kind) {
+				continue
+			}
+			total++
+			for _, v := range e.values(p, kind) {
+				counts[v.value]++
+				titles[v.value] = v.title
+			}
+		}
+		if len(counts) == 0 {
+			continue
+		}
+
+		facet := Facet{}
+		facet.Metadata.Title.SingleString = e.title(kind)
+		facet.Links = append(facet.Links, e.link(kind, "", "All", total, sel))
+		for _, v := range e.order(kind, counts, titles) {
+			facet.Links = append(facet.Links, e.link(kind, v, titles[v], counts[v], sel))
+		}
+		facets = append(facets, facet)
+	}
+	return facets
+}
+
+func (e *FacetEngine) title(kind string) string {
+	if t, ok := e.Titles[kind]; ok {
+		return t
+	}
+	return kind
+}
+
+// match check the publication against the selection, skipping the facet
+// kind
+func (e *FacetEngine) match(p *Publication, sel FacetSelection, skip string) bool {
+	for kind, selected := range sel {
+		if kind == skip || selected == "" {
+			continue
+		}
+		found := false
+		for _, v := range e.values(p, kind) {
+			if v.value == selected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// order sort the values of a facet group, the fixed dimensions keep their
+// natural order and the others are sorted by count
+func (e *FacetEngine) order(kind string, counts map[string]int, titles map[string]string) []string {
+	var values []string
+	switch kind {
+	case FacetPrice:
+		for _, r := range e.PriceRanges {
+			if counts[r.Value] > 0 {
+				values = append(values, r.Value)
+			}
+		}
+		return values
+	case FacetAvailability:
+		for _, a := range availabilities {
+			if counts[a.value] > 0 {
+				values = append(values, a.value)
+			}
+		}
+		return values
+	}
+
+	for v := range counts {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return strings.ToLower(titles[values[i]]) < strings.ToLower(titles[values[j]])
+	})
+	if (kind == FacetAuthor || kind == FacetSubject) && e.MaxValues > 0 && len(values) > e.MaxValues {
+		values = values[:e.MaxValues]
+	}
+	return values
+}
+
+// link build the facet link selecting value for kind, an empty value
+// clears the selection of the group
+func (e *FacetEngine) link(kind string, value string, title string, count int, sel FacetSelection) *Link {
+	u, err := url.Parse(e.BaseURL)
+	if err != nil {
+		u = &url.URL{Path: e.BaseURL}
+	}
+	q := u.Query()
+	q.Del("page")
+	for k, v := range sel {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	if value == "" {
+		q.Del(kind)
+	} else {
+		q.Set(kind, value)
+	}
+	u.RawQuery = q.Encode()
+
+	l := &Link{
+		Href:       u.String(),
+		TypeLink:   mediatype.OPDS2,
+		Title:      title,
+		Properties: &Properties{NumberOfItems: count},
+	}
+	if sel[kind] == value {
+		l.Rel = []string{rel.Self}
+	}
+	return l
+}
+
+type facetValue struct {
+	value string
+	title string
+}
+
+var availabilities = []facetValue{
+	{"free", "Free"},
+	{"buy", "Buy"},
+	{"borrow", "Borrow"},
+	{"subscribe", "Subscription"},
+	{"sample", "Sample"},
+}
+
+// values return the facet values of a publication for kind
+func (e *FacetEngine) values(p *Publication, kind string) []facetValue {
+	var values []facetValue
+	add := func(value string, title string) {
+		for _, v := range values {
+			if v.value == value {
+				return
+			}
+		}
+		values = append(values, facetValue{value, title})
+	}
+
+	switch kind {
+	case FacetLanguage:
+		for _, l := range p.Metadata.Language {
+			add(strings.ToLower(l), languageName(l))
+		}
+	case FacetFormat:
+		for _, l := range p.Links {
+			if l.IsAcquisition() {
+				for _, mt := range acquiredTypes(l) {
+					add(mt, mediatype.Name(mt))
+				}
+			}
+		}
+	case FacetSubject:
+		for _, s := range p.Metadata.Subject {
+			add(strings.ToLower(s.Name), s.Name)
+		}
+	case FacetAuthor:
+		for _, a := range p.Metadata.Author {
+			add(strings.ToLower(a.Name.String()), a.Name.String())
+		}
+	case FacetAvailability:
+		for _, l := range p.Links {
+			if a := availability(l); a != "" {
+				for _, v := range availabilities {
+					if v.value == a {
+						add(v.value, v.title)
+					}
+				}
+			}
+		}
+	case FacetPrice:
+		price, ok := lowestPrice(p, e.Currency)
+		if !ok {
+			break
+		}
+		for _, r := range e.PriceRanges {
+			if r.Value == "free" {
+				ok = price == 0
+			} else {
+				ok = price > 0 && price >= r.Min && (r.Max == 0 || price < r.Max)
+			}
+			if ok {
+				add(r.Value, r.Title)
+			}
+		}
+	}
+	return values
+}
+
+// availability return the availability value of an acquisition link
+func availability(l *Link) string {
+	for _, r := range l.Rel {
+		switch r {
+		case rel.AcquisitionOpenAccess:
+			return "free"
+		case rel.AcquisitionBuy:
+			return "buy"
+		case rel.AcquisitionBorrow:
+			return "borrow"
+		case rel.AcquisitionSubscribe:
+			return "subscribe"
+		case rel.AcquisitionSample, rel.Preview:
+			return "sample"
+		case rel.Acquisition:
+			if l.Properties == nil || l.Properties.Price.IsFree() {
+				return "free"
+			}
+			return "buy"
+		}
+	}
+	return ""
+}
+
+// lowestPrice return the lowest price in currency of the acquisition
+// links, open access links cost nothing, the prices in other currencies
+// are skipped
+func lowestPrice(p *Publication, currency string) (float64, bool) {
+	var price Decimal
+	found := false
+	for _, l := range p.Links {
+		var v Decimal
+		switch a := availability(l); {
+		case a == "free":
+		case a == "buy" && l.Properties != nil:
+			priced := false
+			for _, pr := range l.Properties.Price {
+				if !strings.EqualFold(pr.Currency, currency) {
+					continue
+				}
+				if !priced || pr.Value.Cmp(v) < 0 {
+					v, priced = pr.Value, true
+				}
+			}
+			if !priced {
+				continue
+			}
+		default:
+			continue
+		}
+		if !found || v.Cmp(price) < 0 {
+			price, found = v, true
+		}
+	}
+	return price.Float64(), found
+}
+
+// acquiredTypes return the media types obtained through an acquisition
+// link, the leaves of its indirect acquisitions or the link type
+func acquiredTypes(l *Link) []string {
+	var types []string
+	var walk func(ias []IndirectAcquisition)
+	walk = func(ias []IndirectAcquisition) {
+		for _, ia := range ias {
+			if len(ia.Child) == 0 {
+				types = append(types, ia.TypeAcquisition)
+			}
+			walk(ia.Child)
+		}
+	}
+	if l.Properties != nil {
+		walk(l.Properties.IndirectAcquisition)
+	}
+	if len(types) == 0 && l.TypeLink != "" {
+		types = append(types, mediatype.Base(l.TypeLink))
+	}
+	return types
+}
+
+func languageName(tag string) string {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return tag
+	}
+	if name := display.Self.Name(t); name != "" {
+		return name
+	}
+	return tag
+}
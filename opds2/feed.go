@@ -16,16 +16,19 @@ type Feed struct {
 	Groups       []Group       `json:"groups,omitempty"`
 	Publications []Publication `json:"publications,omitempty"`
 	Navigation   Links         `json:"navigation,omitempty"`
+	// BaseURL is the URL the feed was read from, relative hrefs resolve
+	// against it
+	BaseURL string `json:"-"`
 }
 
 // Metadata has a limited subset of metadata compared to a publication
 type Metadata struct {
-	RDFType       string     `json:"@type,omitempty"`
-	Title         string     `json:"title"`
-	NumberOfItems int        `json:"numberOfItems,omitempty"`
-	ItemsPerPage  int        `json:"itemsPerPage,omitempty"`
-	CurrentPage   int        `json:"currentPage,omitempty"`
-	Modified      *time.Time `json:"modified,omitempty"`
+	RDFType       string        `json:"@type,omitempty"`
+	Title         MultiLanguage `json:"title"`
+	NumberOfItems int           `json:"numberOfItems,omitempty"`
+	ItemsPerPage  int           `json:"itemsPerPage,omitempty"`
+	CurrentPage   int           `json:"currentPage,omitempty"`
+	Modified      *time.Time    `json:"modified,omitempty"`
 }
 
 // Facet is a collection that contains a facet group
@@ -46,8 +49,55 @@ type Group struct {
 // Use also in Rendition for fxl
 type Properties struct {
 	NumberOfItems       int                   `json:"numberOfItems,omitempty"`
-	Price               *Price                `json:"price,omitempty"`
+	Price               Prices                `json:"price,omitempty"`
 	IndirectAcquisition []IndirectAcquisition `json:"indirectAcquisition,omitempty"`
+	Availability        *Availability         `json:"availability,omitempty"`
+	Holds               *Holds                `json:"holds,omitempty"`
+	Copies              *Copies               `json:"copies,omitempty"`
+	// LCPHashedPassphrase is the hex encoded SHA-256 hash of the user
+	// passphrase of an LCP license, given by the store
+	LCPHashedPassphrase string     `json:"lcp_hashed_passphrase,omitempty"`
+	Encrypted           *Encrypted `json:"encrypted,omitempty"`
+	// Provenance is the upstream catalog of a link in a merged feed
+	Provenance string `json:"provenance,omitempty"`
+}
+
+// Encrypted describe the encryption of the resource of a link, Scheme is
+// the DRM, like LCP, Profile its encryption profile and Algorithm the
+// algorithm of the resource encryption
+type Encrypted struct {
+	Scheme    string `json:"scheme"`
+	Profile   string `json:"profile,omitempty"`
+	Algorithm string `json:"algorithm"`
+}
+
+// Availability states of an acquisition link
+const (
+	AvailabilityAvailable   = "available"
+	AvailabilityUnavailable = "unavailable"
+	AvailabilityReserved    = "reserved"
+	AvailabilityReady       = "ready"
+)
+
+// Availability of an acquisition link, reserved means the user has a hold
+// and ready that the hold can be borrowed until Until
+type Availability struct {
+	State string     `json:"state"`
+	Since *time.Time `json:"since,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// Holds is the queue of holds on a lending acquisition link, Position is
+// the place of the user in the queue
+type Holds struct {
+	Total    int `json:"total"`
+	Position int `json:"position,omitempty"`
+}
+
+// Copies is the number of copies of a lending acquisition link
+type Copies struct {
+	Total     int `json:"total"`
+	Available int `json:"available"`
 }
 
 // IndirectAcquisition store
@@ -56,12 +106,6 @@ type IndirectAcquisition struct {
 	Child           []IndirectAcquisition `json:"child,omitempty"`
 }
 
-// Price price information
-type Price struct {
-	Currency string  `json:"currency"`
-	Value    float64 `json:"value"`
-}
-
 // MultiLanguage store a basic string when we only have one lang
 // Store in a hash by language for multiple string representation
 type MultiLanguage struct {
@@ -77,7 +121,7 @@ type StringOrArray []string
 func New(title string) Feed {
 	var feed Feed
 
-	feed.Metadata.Title = title
+	feed.Metadata.Title.SingleString = title
 	t := time.Now()
 	feed.Metadata.Modified = &t
 
@@ -94,13 +138,9 @@ func (m MultiLanguage) MarshalJSON() ([]byte, error) {
 	return json.Marshal(m.SingleString)
 }
 
+// String return the string in the default language
 func (m MultiLanguage) String() string {
-	if len(m.MultiString) > 0 {
-		for _, s := range m.MultiString {
-			return s
-		}
-	}
-	return m.SingleString
+	return m.Get()
 }
 
 // MarshalJSON overwrite json marshalling for handling string or array
@@ -108,5 +148,5 @@ func (r StringOrArray) MarshalJSON() ([]byte, error) {
 	if len(r) == 1 {
 		return json.Marshal(r[0])
 	}
-	return json.Marshal(r)
+	return json.Marshal([]string(r))
 }
@@ -1,10 +1,18 @@
 package opds2
 
+import (
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
 // AddLink add a new link in feed information
 // at minimum the self link
 func (feed *Feed) AddLink(href string, rel string, typeLink string, templated bool) {
 	l := NewLink(href)
 	l.TypeLink = typeLink
+	if rel != "" {
+		l.Rel = []string{rel}
+	}
 	if templated == true {
 		l.Templated = true
 	}
@@ -17,6 +25,9 @@ func (feed *Feed) AddNavigation(title string, href string, rel string, typeLink
 	l := NewLink(href)
 
 	l.TypeLink = typeLink
+	if rel != "" {
+		l.Rel = []string{rel}
+	}
 	if title != "" {
 		l.Title = title
 	}
@@ -32,16 +43,16 @@ func (feed *Feed) AddPagination(numberItems int, itemsPerPage int, currentPage i
 	feed.Metadata.NumberOfItems = numberItems
 
 	if nextLink != "" {
-		feed.AddLink(nextLink, "next", "application/opds+json", false)
+		feed.AddLink(nextLink, rel.Next, mediatype.OPDS2, false)
 	}
 	if prevLink != "" {
-		feed.AddLink(prevLink, "previous", "application/opds+json", false)
+		feed.AddLink(prevLink, rel.Previous, mediatype.OPDS2, false)
 	}
 	if firstLink != "" {
-		feed.AddLink(firstLink, "first", "application/opds+json", false)
+		feed.AddLink(firstLink, rel.First, mediatype.OPDS2, false)
 	}
 	if lastLink != "" {
-		feed.AddLink(lastLink, "last", "application/opds+json", false)
+		feed.AddLink(lastLink, rel.Last, mediatype.OPDS2, false)
 	}
 }
 
@@ -50,13 +61,13 @@ func (feed *Feed) AddFacet(link *Link, group string) {
 	var facet Facet
 
 	for i, f := range feed.Facets {
-		if f.Metadata.Title == group {
+		if f.Metadata.Title.String() == group {
 			feed.Facets[i].Links = append(feed.Facets[i].Links, link)
 			return
 		}
 	}
 
-	facet.Metadata.Title = group
+	facet.Metadata.Title.SingleString = group
 	facet.Links = append(facet.Links, link)
 	feed.Facets = append(feed.Facets, facet)
 }
@@ -74,9 +85,9 @@ func (feed *Feed) AddPublicationInGroup(publication Publication, collLink *Link)
 		}
 	}
 
-	group.Metadata.Title = collLink.Title
+	group.Metadata.Title.SingleString = collLink.Title
 	group.Publications = append(group.Publications, publication)
-	group.Links = append(group.Links, &Link{Rel: []string{"self"}, Title: collLink.Title, Href: collLink.Href})
+	group.Links = append(group.Links, &Link{Rel: []string{rel.Self}, Title: collLink.Title, Href: collLink.Href})
 	feed.Groups = append(feed.Groups, group)
 }
 
@@ -93,8 +104,8 @@ func (feed *Feed) AddNavigationInGroup(link *Link, collLink *Link) {
 		}
 	}
 
-	group.Metadata.Title = collLink.Title
+	group.Metadata.Title.SingleString = collLink.Title
 	group.Navigation = append(group.Navigation, link)
-	group.Links = append(group.Links, &Link{Rel: []string{"self"}, Title: collLink.Title, Href: collLink.Href})
+	group.Links = append(group.Links, &Link{Rel: []string{rel.Self}, Title: collLink.Title, Href: collLink.Href})
 	feed.Groups = append(feed.Groups, group)
 }
@@ -0,0 +1,324 @@
+package opds2
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Types of the identifiers recognized by ParseIdentifier
+const (
+	IdentifierISBN  = "isbn"
+	IdentifierUUID  = "uuid"
+	IdentifierDOI   = "doi"
+	IdentifierASIN  = "asin"
+	IdentifierURN   = "urn"
+	IdentifierURI   = "uri"
+	IdentifierOther = "other"
+)
+
+// ErrChecksum is returned for an ISBN whose check digit is wrong
+var ErrChecksum = errors.New("opds2: invalid ISBN check digit")
+
+// Identifier is a parsed publication identifier, Value is normalized: the
+// 13 digits of an ISBN, a lowercase UUID, a DOI without resolver, an
+// uppercase ASIN
+type Identifier struct {
+	Type  string
+	Value string
+	// Raw is the identifier as written
+	Raw string
+}
+
+var (
+	uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	doiPattern  = regexp.MustCompile(`^10\.[0-9]{4,9}(\.[0-9]+)*/\S+$`)
+	asinPattern = regexp.MustCompile(`^[0-9A-Z]{10}$`)
+)
+
+// doiResolvers are the prefixes of DOI given as URLs
+var doiResolvers = []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/"}
+
+// ParseIdentifier recognize an identifier:
+//
+//   - urn:isbn:, isbn: and bare ISBN-10 or ISBN-13 with or without
+//     hyphens, the check digit is verified and ISBN-10 converted to 13
+//     digits
+//   - urn:uuid:, uuid: and bare UUID
+//   - urn:doi:, doi:, doi.org URLs and bare DOI like 10.1000/182
+//   - urn:asin:, asin: and bare ASIN starting with B0
+//   - other URN and URI are kept as written
+//
+// An identifier with a prefix but an invalid value is an error, a bare
+// value that is none of these is of type IdentifierOther.
+func ParseIdentifier(s string) (Identifier, error) {
+	raw := s
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+	id := Identifier{Raw: raw}
+
+	if value, ok := cutPrefix(s, "urn:isbn:", "isbn:", "isbn-13:", "isbn-10:", "isbn "); ok {
+		isbn, err := NormalizeISBN(value)
+		if err != nil {
+			return id, err
+		}
+		id.Type, id.Value = IdentifierISBN, isbn
+		return id, nil
+	}
+	if value, ok := cutPrefix(s, "urn:uuid:", "uuid:"); ok {
+		value = strings.ToLower(value)
+		if !uuidPattern.MatchString(value) {
+			return id, fmt.Errorf("opds2: invalid UUID %q", value)
+		}
+		id.Type, id.Value = IdentifierUUID, value
+		return id, nil
+	}
+	if value, ok := cutPrefix(s, append([]string{"urn:doi:", "doi:"}, doiResolvers...)...); ok {
+		if !doiPattern.MatchString(value) {
+			return id, fmt.Errorf("opds2: invalid DOI %q", value)
+		}
+		id.Type, id.Value = IdentifierDOI, value
+		return id, nil
+	}
+	if value, ok := cutPrefix(s, "urn:asin:", "asin:"); ok {
+		value = strings.ToUpper(value)
+		if !asinPattern.MatchString(value) {
+			return id, fmt.Errorf("opds2: invalid ASIN %q", value)
+		}
+		id.Type, id.Value = IdentifierASIN, value
+		return id, nil
+	}
+
+	switch {
+	case strings.HasPrefix(lower, "urn:"):
+		id.Type, id.Value = IdentifierURN, s
+	case strings.Contains(s, ":"):
+		id.Type, id.Value = IdentifierURI, s
+	case uuidPattern.MatchString(lower):
+		id.Type, id.Value = IdentifierUUID, lower
+	case doiPattern.MatchString(s):
+		id.Type, id.Value = IdentifierDOI, s
+	default:
+		if isbn, err := NormalizeISBN(s); err == nil {
+			id.Type, id.Value = IdentifierISBN, isbn
+		} else if upper := strings.ToUpper(s); asinPattern.MatchString(upper) && strings.HasPrefix(upper, "B0") {
+			id.Type, id.Value = IdentifierASIN, upper
+		} else {
+			id.Type, id.Value = IdentifierOther, s
+		}
+	}
+	return id, nil
+}
+
+// cutPrefix return s without the first of prefixes it starts with, ignoring
+// case
+func cutPrefix(s string, prefixes ...string) (string, bool) {
+	for _, p := range prefixes {
+		if len(s) >= len(p) && strings.EqualFold(s[:len(p)], p) {
+			return strings.TrimSpace(s[len(p):]), true
+		}
+	}
+	return s, false
+}
+
+// URN return the identifier as an URI: urn:isbn:, urn:uuid:, urn:doi: or
+// urn:asin: followed by the normalized value, the other identifiers as
+// written
+func (id Identifier) URN() string {
+	switch id.Type {
+	case IdentifierISBN, IdentifierUUID, IdentifierDOI, IdentifierASIN:
+		return "urn:" + id.Type + ":" + id.Value
+	}
+	return strings.TrimSpace(id.Raw)
+}
+
+// String return the URN of the identifier
+func (id Identifier) String() string {
+	return id.URN()
+}
+
+// Key return the canonical key of the identifier, equal for the identifiers
+// of the same publication however they are written: the URN, lowercased
+// but for the values of the other identifiers, as DOI are case insensitive
+func (id Identifier) Key() string {
+	switch id.Type {
+	case IdentifierOther, IdentifierURI:
+		return strings.TrimSpace(id.Raw)
+	}
+	return strings.ToLower(id.URN())
+}
+
+// NormalizeIdentifier return the canonical key of an identifier, used by
+// Merge to find the same publication in several catalogs, the identifier
+// trimmed when it can't be parsed
+func NormalizeIdentifier(s string) string {
+	id, err := ParseIdentifier(s)
+	if err != nil {
+		return strings.TrimSpace(s)
+	}
+	return id.Key()
+}
+
+// Identifiers return the identifier and the alternate identifiers of the
+// publication that can be parsed, in this order
+func (m *PublicationMetadata) Identifiers() []Identifier {
+	var ids []Identifier
+	for _, s := range append([]string{m.Identifier}, m.AltIdentifier...) {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		if id, err := ParseIdentifier(s); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ISBN return the ISBN-13 of the publication from its identifier or its
+// alternate identifiers, empty when it has none
+func (m *PublicationMetadata) ISBN() string {
+	for _, id := range m.Identifiers() {
+		if id.Type == IdentifierISBN {
+			return id.Value
+		}
+	}
+	return ""
+}
+
+// IdentifierKeys return the canonical keys of the identifier and of the
+// alternate identifiers of the publication
+func (m *PublicationMetadata) IdentifierKeys() []string {
+	var keys []string
+	for _, s := range append([]string{m.Identifier}, m.AltIdentifier...) {
+		if k := NormalizeIdentifier(s); k != "" && !containsString(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// AddAltIdentifier add id to the alternate identifiers unless it is the
+// identifier or already one of them
+func (m *PublicationMetadata) AddAltIdentifier(id string) {
+	key := NormalizeIdentifier(id)
+	if key == "" || containsString(m.IdentifierKeys(), key) {
+		return
+	}
+	m.AltIdentifier = append(m.AltIdentifier, strings.TrimSpace(id))
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeISBN return the 13 digits of an ISBN-10 or ISBN-13 written with
+// or without hyphens and spaces, after checking its check digit
+func NormalizeISBN(s string) (string, error) {
+	digits := isbnDigits(s)
+	switch len(digits) {
+	case 10:
+		if !validISBN10(digits) {
+			return "", ErrChecksum
+		}
+		return isbn13("978" + digits[:9]), nil
+	case 13:
+		if !strings.HasPrefix(digits, "978") && !strings.HasPrefix(digits, "979") || !validISBN13(digits) {
+			return "", ErrChecksum
+		}
+		return digits, nil
+	}
+	return "", fmt.Errorf("opds2: invalid ISBN %q", s)
+}
+
+// ISBN10To13 convert an ISBN-10 to ISBN-13
+func ISBN10To13(s string) (string, error) {
+	digits := isbnDigits(s)
+	if len(digits) != 10 {
+		return "", fmt.Errorf("opds2: invalid ISBN-10 %q", s)
+	}
+	return NormalizeISBN(digits)
+}
+
+// ISBN13To10 convert an ISBN-13 starting with 978 to ISBN-10, the 979
+// ISBN have no ISBN-10
+func ISBN13To10(s string) (string, error) {
+	isbn, err := NormalizeISBN(s)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(isbn, "978") {
+		return "", fmt.Errorf("opds2: ISBN %s has no ISBN-10", isbn)
+	}
+	body := isbn[3:12]
+	sum := 0
+	for i, r := range body {
+		sum += (10 - i) * int(r-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X", nil
+	}
+	return body + string(rune('0'+check)), nil
+}
+
+// ValidISBN check the length and the check digit of an ISBN-10 or ISBN-13
+func ValidISBN(s string) bool {
+	_, err := NormalizeISBN(s)
+	return err == nil
+}
+
+// isbnDigits strip the hyphens and spaces of an ISBN, empty when it has
+// other characters
+func isbnDigits(s string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case (r == 'x' || r == 'X') && i == len(strings.TrimSpace(s))-1:
+			b.WriteRune('X')
+		case r == '-' || r == ' ':
+		default:
+			return ""
+		}
+	}
+	return b.String()
+}
+
+func validISBN10(digits string) bool {
+	sum := 0
+	for i, r := range digits {
+		d := int(r - '0')
+		if r == 'X' {
+			if i != 9 {
+				return false
+			}
+			d = 10
+		}
+		sum += (10 - i) * d
+	}
+	return sum%11 == 0
+}
+
+func validISBN13(digits string) bool {
+	return strings.IndexByte(digits, 'X') < 0 && isbn13(digits[:12]) == digits
+}
+
+// isbn13 add the check digit to the 12 first digits of an ISBN-13
+func isbn13(body string) string {
+	sum := 0
+	for i, r := range body {
+		d := int(r - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return body + string(rune('0'+(10-sum%10)%10))
+}
@@ -0,0 +1,211 @@
+package opds2
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLanguage is the language of the string given when none of the
+// requested languages is available, then the undetermined language and
+// the first language in alphabetical order are used
+var DefaultLanguage = language.English
+
+// undetermined is the BCP 47 tag of a string without language
+const undetermined = "und"
+
+// Get return the string in the language best matching tags, tags are in
+// order of preference and fall back on their parent languages, en-GB
+// matches en and zh-TW matches zh-Hant
+func (m MultiLanguage) Get(tags ...language.Tag) string {
+	if len(m.MultiString) == 0 {
+		return m.SingleString
+	}
+	langs := m.Languages()
+	if lang, ok := matchLanguage(langs, tags); ok {
+		return m.MultiString[lang]
+	}
+	if lang, ok := matchLanguage(langs, []language.Tag{DefaultLanguage}); ok {
+		return m.MultiString[lang]
+	}
+	if s, ok := m.MultiString[undetermined]; ok {
+		return s
+	}
+	return m.MultiString[langs[0]]
+}
+
+// Set set the string in lang, an empty lang set the string without
+// language, kept as undetermined once the string has languages
+func (m *MultiLanguage) Set(lang string, value string) {
+	if lang == "" && len(m.MultiString) == 0 {
+		m.SingleString = value
+		return
+	}
+	if lang == "" {
+		lang = undetermined
+	}
+	if m.MultiString == nil {
+		m.MultiString = make(map[string]string)
+		if m.SingleString != "" {
+			m.MultiString[undetermined] = m.SingleString
+		}
+		m.SingleString = ""
+	}
+	m.MultiString[lang] = value
+}
+
+// Languages return the languages of the string in alphabetical order,
+// empty for a string without language
+func (m MultiLanguage) Languages() []string {
+	var langs []string
+	for lang := range m.MultiString {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// IsEmpty check if the string has no value in any language
+func (m MultiLanguage) IsEmpty() bool {
+	return m.SingleString == "" && len(m.MultiString) == 0
+}
+
+// matchLanguage return the language of langs best matching tags, false
+// when none match
+func matchLanguage(langs []string, tags []language.Tag) (string, bool) {
+	if len(tags) == 0 {
+		return "", false
+	}
+	var supported []language.Tag
+	var keys []string
+	for _, lang := range langs {
+		t, err := language.Parse(lang)
+		if err != nil || t == language.Und {
+			continue
+		}
+		supported = append(supported, t)
+		keys = append(keys, lang)
+	}
+	if len(supported) == 0 {
+		return "", false
+	}
+	_, i, confidence := language.NewMatcher(supported).Match(tags...)
+	if confidence == language.No {
+		return "", false
+	}
+	return keys[i], true
+}
+
+// ParseAcceptLanguage return the languages of an Accept-Language header in
+// order of preference, invalid headers give no language
+func ParseAcceptLanguage(header string) []language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return nil
+	}
+	return tags
+}
+
+// AcceptLanguage format tags as an Accept-Language header, in order of
+// preference
+func AcceptLanguage(tags ...language.Tag) string {
+	var parts []string
+	for i, t := range tags {
+		if i == 0 {
+			parts = append(parts, t.String())
+			continue
+		}
+		q := math.Max(1-float64(i)/10, 0.1)
+		parts = append(parts, t.String()+";q="+strconv.FormatFloat(q, 'f', 1, 64))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Localize return a copy of the feed where every title and name is in the
+// language best matching tags, for formats and renderings showing a single
+// language
+func (feed *Feed) Localize(tags ...language.Tag) *Feed {
+	f := *feed
+	f.Metadata = feed.Metadata.localize(tags)
+	f.Facets = nil
+	for _, facet := range feed.Facets {
+		facet.Metadata = facet.Metadata.localize(tags)
+		f.Facets = append(f.Facets, facet)
+	}
+	f.Groups = nil
+	for _, g := range feed.Groups {
+		g.Metadata = g.Metadata.localize(tags)
+		g.Publications = localizePublications(g.Publications, tags)
+		f.Groups = append(f.Groups, g)
+	}
+	f.Publications = localizePublications(feed.Publications, tags)
+	return &f
+}
+
+// Localize return a copy of the publication where the title, the names of
+// the contributors and of the collections are in the language best
+// matching tags
+func (publication *Publication) Localize(tags ...language.Tag) *Publication {
+	p := *publication
+	m := &p.Metadata
+	m.Title = MultiLanguage{SingleString: m.Title.Get(tags...)}
+	for _, cs := range m.contributors() {
+		*cs = localizeContributors(*cs, tags)
+	}
+	if m.BelongsTo != nil {
+		m.BelongsTo = &BelongsTo{
+			Series:     localizeCollections(m.BelongsTo.Series, tags),
+			Collection: localizeCollections(m.BelongsTo.Collection, tags),
+		}
+	}
+	return &p
+}
+
+func (m Metadata) localize(tags []language.Tag) Metadata {
+	m.Title = MultiLanguage{SingleString: m.Title.Get(tags...)}
+	return m
+}
+
+func localizePublications(pubs []Publication, tags []language.Tag) []Publication {
+	if pubs == nil {
+		return nil
+	}
+	res := make([]Publication, len(pubs))
+	for i := range pubs {
+		res[i] = *pubs[i].Localize(tags...)
+	}
+	return res
+}
+
+func localizeContributors(cs Contributors, tags []language.Tag) Contributors {
+	if cs == nil {
+		return nil
+	}
+	res := make(Contributors, len(cs))
+	for i, c := range cs {
+		lc := *c
+		lc.Name = MultiLanguage{SingleString: c.Name.Get(tags...)}
+		res[i] = &lc
+	}
+	return res
+}
+
+func localizeCollections(cs Collections, tags []language.Tag) Collections {
+	if cs == nil {
+		return nil
+	}
+	res := make(Collections, len(cs))
+	for i, c := range cs {
+		lc := *c
+		if c.Contributor != nil {
+			name := *c.Contributor
+			name.Name = MultiLanguage{SingleString: c.Name.Get(tags...)}
+			lc.Contributor = &name
+		}
+		res[i] = &lc
+	}
+	return res
+}
@@ -1,6 +1,11 @@
 package opds2
 
-import "strings"
+import (
+	"time"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/rel"
+)
 
 // Links used in collections and links
 type Links []*Link
@@ -29,22 +34,114 @@ func NewLink(data any) *Link {
 	return parseLink(data)
 }
 
-func (links Links) FindFirstLinkByRel(rel string) *Link {
+// FindFirstLinkByRel return the first link with the relation r, an empty
+// link otherwise
+func (links Links) FindFirstLinkByRel(r string) *Link {
 	for _, l := range links {
-		for _, r := range l.Rel {
-			if r == rel {
-				return l
-			}
+		if l.HasRel(r) {
+			return l
 		}
 	}
 	return &Link{}
 }
 
+// FindFirstLinkByType return the first link whose type matches mt, see
+// mediatype.Matches, an empty link otherwise
 func (links Links) FindFirstLinkByType(mt string) *Link {
+	if l := links.FilterByType(mt); len(l) > 0 {
+		return l[0]
+	}
+	return &Link{}
+}
+
+// FilterByRel return the links with the relation r
+func (links Links) FilterByRel(r string) Links {
+	var res Links
 	for _, l := range links {
-		if strings.Contains(l.TypeLink, mt) {
-			return l
+		if l.HasRel(r) {
+			res = append(res, l)
 		}
 	}
-	return &Link{}
+	return res
+}
+
+// FilterByType return the links whose type matches mt, the parameters of
+// mt must be set on the link, so application/atom+xml matches every OPDS 1
+// feed
+func (links Links) FilterByType(mt string) Links {
+	pattern, err := mediatype.Parse(mt)
+	if err != nil {
+		return nil
+	}
+	var res Links
+	for _, l := range links {
+		if m, err := mediatype.Parse(l.TypeLink); err == nil && pattern.Matches(m) {
+			res = append(res, l)
+		}
+	}
+	return res
+}
+
+// Acquisitions return the acquisition links
+func (links Links) Acquisitions() Links {
+	var res Links
+	for _, l := range links {
+		if l.IsAcquisition() {
+			res = append(res, l)
+		}
+	}
+	return res
+}
+
+// HasRel check if the link has the relation r
+func (l *Link) HasRel(r string) bool {
+	for _, lr := range l.Rel {
+		if lr == r {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAcquisition check if the link has an acquisition relation
+func (l *Link) IsAcquisition() bool {
+	for _, r := range l.Rel {
+		if rel.IsAcquisition(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// MediaType return the parsed type of the link, empty when it doesn't
+// parse
+func (l *Link) MediaType() mediatype.MediaType {
+	m, _ := mediatype.Parse(l.TypeLink)
+	return m
+}
+
+// IsAvailable check if the link can be acquired at the time now, a link
+// without availability is available unless none of its copies are left
+func (l *Link) IsAvailable(now time.Time) bool {
+	if l.Properties == nil {
+		return true
+	}
+	if a := l.Properties.Availability; a != nil {
+		switch a.State {
+		case AvailabilityAvailable, AvailabilityReady:
+		default:
+			return false
+		}
+		if a.Since != nil && now.Before(*a.Since) {
+			return false
+		}
+		if a.Until != nil && now.After(*a.Until) {
+			return false
+		}
+		return true
+	}
+	if c := l.Properties.Copies; c != nil {
+		return c.Available > 0
+	}
+	return true
 }
@@ -0,0 +1,343 @@
+package opds2
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// MergeOptions configure how feeds are aggregated by Merge
+type MergeOptions struct {
+	// Title of the merged feed, defaults to the title of the first feed
+	Title string
+	// Self is the href of the merged feed self link
+	Self string
+	// Sources name the upstream catalogs in the order of the feeds, it is
+	// recorded as the provenance of their links, the self link or the
+	// title of a feed is used when missing
+	Sources []string
+}
+
+// Merge aggregate feeds into one, publications sharing an identifier or an
+// alternate identifier (compared by NormalizeIdentifier) are merged into
+// one publication holding the acquisition links of every source, facets
+// and groups are merged by title, the merged feed is modified at the date
+// of its newest source, or now when no source has one
+func Merge(opts MergeOptions, feeds ...*Feed) Feed {
+	merged := New(opts.Title)
+	merged.Links = Links{}
+	merged.Metadata.Modified = nil
+	if opts.Self != "" {
+		merged.AddLink(opts.Self, rel.Self, mediatype.OPDS2, false)
+	}
+
+	pubs := newPublicationSet()
+	groups := make(map[string]*publicationGroup)
+	var groupOrder []string
+	facets := make(map[string]int)
+
+	for i, feed := range feeds {
+		if feed == nil {
+			continue
+		}
+		source := feedSource(opts, i, feed)
+		if merged.Metadata.Title.IsEmpty() {
+			merged.Metadata.Title = feed.Metadata.Title
+		}
+		if m := feed.Metadata.Modified; m != nil && (merged.Metadata.Modified == nil || m.After(*merged.Metadata.Modified)) {
+			t := *feed.Metadata.Modified
+			merged.Metadata.Modified = &t
+		}
+
+		for j := range feed.Publications {
+			pubs.add(&feed.Publications[j], source)
+		}
+		merged.Navigation = mergeLinks(merged.Navigation, feed.Navigation, source)
+
+		for _, f := range feed.Facets {
+			k, ok := facets[f.Metadata.Title.String()]
+			if !ok {
+				k = len(merged.Facets)
+				facets[f.Metadata.Title.String()] = k
+				merged.Facets = append(merged.Facets, Facet{Metadata: f.Metadata})
+			}
+			merged.Facets[k].Links = mergeLinks(merged.Facets[k].Links, f.Links, source)
+		}
+
+		for _, g := range feed.Groups {
+			mg, ok := groups[g.Metadata.Title.String()]
+			if !ok {
+				mg = &publicationGroup{Group: Group{Metadata: g.Metadata}, pubs: newPublicationSet()}
+				groups[g.Metadata.Title.String()] = mg
+				groupOrder = append(groupOrder, g.Metadata.Title.String())
+			}
+			mg.Links = mergeLinks(mg.Links, g.Links, source)
+			mg.Navigation = mergeLinks(mg.Navigation, g.Navigation, source)
+			for j := range g.Publications {
+				mg.pubs.add(&g.Publications[j], source)
+			}
+		}
+	}
+
+	merged.Publications = pubs.publications()
+	merged.Metadata.NumberOfItems = len(merged.Publications)
+	for _, title := range groupOrder {
+		g := groups[title]
+		g.Publications = g.pubs.publications()
+		if len(g.Publications) > 0 {
+			g.Metadata.NumberOfItems = len(g.Publications)
+		}
+		merged.Groups = append(merged.Groups, g.Group)
+	}
+	if merged.Metadata.Modified == nil {
+		now := time.Now()
+		merged.Metadata.Modified = &now
+	}
+
+	return merged
+}
+
+func feedSource(opts MergeOptions, i int, feed *Feed) string {
+	if i < len(opts.Sources) && opts.Sources[i] != "" {
+		return opts.Sources[i]
+	}
+	if self := feed.Links.FindFirstLinkByRel(rel.Self); self.Href != "" {
+		return self.Href
+	}
+	return feed.Metadata.Title.String()
+}
+
+type publicationGroup struct {
+	Group
+	pubs *publicationSet
+}
+
+// publicationSet keep merged publications in the order they were first seen
+type publicationSet struct {
+	index map[string]int
+	pubs  []Publication
+}
+
+func newPublicationSet() *publicationSet {
+	return &publicationSet{index: make(map[string]int)}
+}
+
+func (s *publicationSet) add(p *Publication, source string) {
+	for _, k := range publicationKeys(p) {
+		if i, ok := s.index[k]; ok {
+			mergePublication(&s.pubs[i], p, source)
+			for _, merged := range publicationKeys(&s.pubs[i]) {
+				if _, ok := s.index[merged]; !ok {
+					s.index[merged] = i
+				}
+			}
+			return
+		}
+	}
+	for _, k := range publicationKeys(p) {
+		s.index[k] = len(s.pubs)
+	}
+	s.pubs = append(s.pubs, copyPublication(p, source))
+}
+
+// publicationKeys return the canonical keys of the identifiers of the
+// publication, the href of its first acquisition link when it has none
+func publicationKeys(p *Publication) []string {
+	if keys := p.Metadata.IdentifierKeys(); len(keys) > 0 {
+		return keys
+	}
+	if k := PublicationKey(p); k != "" {
+		return []string{k}
+	}
+	return nil
+}
+
+func (s *publicationSet) publications() []Publication {
+	return s.pubs
+}
+
+// copyPublication copy the links of a publication so that provenance can be
+// recorded without modifying the source feed
+func copyPublication(p *Publication, source string) Publication {
+	c := *p
+	c.Links = mergeLinks(nil, p.Links, source)
+	c.Images = append(Links(nil), p.Images...)
+	m := &c.Metadata
+	m.AltIdentifier = append(StringOrArray(nil), m.AltIdentifier...)
+	m.Language = append(StringOrArray(nil), m.Language...)
+	m.Subject = append(Subjects(nil), m.Subject...)
+	for _, cons := range m.contributors() {
+		*cons = append(Contributors(nil), *cons...)
+	}
+	if m.BelongsTo != nil {
+		b := *m.BelongsTo
+		b.Series = append(Collections(nil), b.Series...)
+		b.Collection = append(Collections(nil), b.Collection...)
+		m.BelongsTo = &b
+	}
+	return c
+}
+
+// mergePublication add the links and metadata of src missing in dst
+func mergePublication(dst, src *Publication, source string) {
+	dst.Links = mergeLinks(dst.Links, src.Links, source)
+	for _, img := range src.Images {
+		if !hasHref(dst.Images, img.Href) {
+			dst.Images = append(dst.Images, img)
+		}
+	}
+
+	dm, sm := &dst.Metadata, &src.Metadata
+	if dm.Identifier == "" {
+		dm.Identifier = sm.Identifier
+	}
+	for _, id := range append([]string{sm.Identifier}, sm.AltIdentifier...) {
+		dm.AddAltIdentifier(id)
+	}
+	dcons, scons := dm.contributors(), sm.contributors()
+	for i := range dcons {
+		for _, c := range *scons[i] {
+			if !hasContributor(*dcons[i], c) {
+				*dcons[i] = append(*dcons[i], c)
+			}
+		}
+	}
+	for _, s := range sm.Subject {
+		if !hasSubject(dm.Subject, s) {
+			dm.Subject = append(dm.Subject, s)
+		}
+	}
+	for _, l := range sm.Language {
+		if !containsFold(dm.Language, l) {
+			dm.Language = append(dm.Language, l)
+		}
+	}
+
+	if dm.RDFType == "" {
+		dm.RDFType = sm.RDFType
+	}
+	if dm.Description == "" {
+		dm.Description = sm.Description
+	}
+	if dm.PublicationDate == nil {
+		dm.PublicationDate = sm.PublicationDate
+	}
+	if sm.Modified != nil && (dm.Modified == nil || sm.Modified.After(*dm.Modified)) {
+		dm.Modified = sm.Modified
+	}
+	if dm.Rights == "" {
+		dm.Rights = sm.Rights
+	}
+	if dm.Source == "" {
+		dm.Source = sm.Source
+	}
+	if dm.Duration == 0 {
+		dm.Duration = sm.Duration
+	}
+	if sm.BelongsTo != nil {
+		if dm.BelongsTo == nil {
+			dm.BelongsTo = &BelongsTo{}
+		}
+		dm.BelongsTo.Series = mergeCollections(dm.BelongsTo.Series, sm.BelongsTo.Series)
+		dm.BelongsTo.Collection = mergeCollections(dm.BelongsTo.Collection, sm.BelongsTo.Collection)
+	}
+}
+
+// contributors return the contributor lists of every role, in a fixed order
+func (m *PublicationMetadata) contributors() []*Contributors {
+	return []*Contributors{
+		&m.Author, &m.Translator, &m.Editor, &m.Artist, &m.Illustrator, &m.Letterer, &m.Penciler,
+		&m.Colorist, &m.Inker, &m.Narrator, &m.Contributor, &m.Publisher, &m.Imprint,
+	}
+}
+
+// mergeLinks append copies of the links whose href is missing in dst,
+// tagged with the source when they have none
+func mergeLinks(dst, src Links, source string) Links {
+	for _, l := range src {
+		if hasLink(dst, l) {
+			continue
+		}
+		c := *l
+		if c.Properties != nil {
+			p := *c.Properties
+			c.Properties = &p
+		} else {
+			c.Properties = &Properties{}
+		}
+		if c.Properties.Provenance == "" {
+			c.Properties.Provenance = source
+		}
+		dst = append(dst, &c)
+	}
+	return dst
+}
+
+func mergeCollections(dst, src Collections) Collections {
+	for _, c := range src {
+		found := false
+		for _, d := range dst {
+			if d.Contributor != nil && c.Contributor != nil && strings.EqualFold(d.Name.String(), c.Name.String()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+func hasLink(links Links, l *Link) bool {
+	for _, o := range links {
+		if o.Href == l.Href && o.TypeLink == l.TypeLink && strings.Join(o.Rel, " ") == strings.Join(l.Rel, " ") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasHref(links Links, href string) bool {
+	for _, l := range links {
+		if l.Href == href {
+			return true
+		}
+	}
+	return false
+}
+
+func hasContributor(cons Contributors, c *Contributor) bool {
+	for _, o := range cons {
+		if c.Identifier != "" && o.Identifier == c.Identifier {
+			return true
+		}
+		if strings.EqualFold(o.Name.String(), c.Name.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasSubject(subjects Subjects, s *Subject) bool {
+	for _, o := range subjects {
+		if s.Code != "" && o.Code == s.Code && o.Scheme == s.Scheme {
+			return true
+		}
+		if strings.EqualFold(o.Name, s.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, v string) bool {
+	for _, o := range values {
+		if strings.EqualFold(o, v) {
+			return true
+		}
+	}
+	return false
+}
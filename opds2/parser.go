@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/cast"
@@ -18,10 +19,18 @@ func ParseURL(url string) (*Feed, error) {
 	if err != nil {
 		return nil, err
 	}
+	return ParseRequest(request)
+}
+
+// ParseRequest send the request and parse the feed of the response, set
+// its Accept-Language header with AcceptLanguage to get the titles in the
+// languages of the user
+func ParseRequest(request *http.Request) (*Feed, error) {
 	res, errReq := http.DefaultClient.Do(request)
 	if errReq != nil {
 		return nil, errReq
 	}
+	defer res.Body.Close()
 
 	buff, errRead := io.ReadAll(res.Body)
 	if errRead != nil {
@@ -32,6 +41,7 @@ func ParseURL(url string) (*Feed, error) {
 	if errParse != nil {
 		return &Feed{}, errParse
 	}
+	feed.BaseURL = res.Request.URL.String()
 
 	return feed, nil
 }
@@ -48,6 +58,7 @@ func ParseFile(filePath string) (*Feed, error) {
 	if errParse != nil {
 		return &Feed{}, errParse
 	}
+	feed.BaseURL = fileURL(filePath)
 
 	return feed, nil
 }
@@ -69,9 +80,9 @@ func ParseBuffer(buff []byte) (*Feed, error) {
 
 // UnmarshalJSON make all unmarshalling by hand to handle all case
 func (feed *Feed) UnmarshalJSON(data []byte) error {
-	info, castErr := cast.ToStringMapE(string(data))
-	if castErr != nil {
-		return castErr
+	info, err := decodeObject(data, "feed")
+	if err != nil {
+		return err
 	}
 
 	for k, v := range info {
@@ -80,7 +91,7 @@ func (feed *Feed) UnmarshalJSON(data []byte) error {
 			switch v.(type) {
 			case string:
 				feed.Context = append(feed.Context, cast.ToString(v))
-			case []string:
+			case []any:
 				feed.Context = cast.ToStringSlice(v)
 			}
 		case "metadata":
@@ -107,14 +118,14 @@ func parseMetadata(data any) Metadata {
 	for k, v := range info {
 		switch k {
 		case "title":
-			m.Title = cast.ToString(v)
+			m.Title = parseMultiLanguage(v)
 		case "numberOfItems":
 			m.NumberOfItems = cast.ToInt(v)
 		case "itemsPerPage":
 			m.ItemsPerPage = cast.ToInt(v)
 		case "modified":
 			m.Modified = parseDate(v)
-		case "type":
+		case "@type", "type":
 			m.RDFType = cast.ToString(v)
 		case "currentPage":
 			m.CurrentPage = cast.ToInt(v)
@@ -145,12 +156,7 @@ func parseLink(data any) *Link {
 		case "type":
 			l.TypeLink = cast.ToString(v)
 		case "rel":
-			switch v.(type) {
-			case string:
-				l.Rel = append(l.Rel, cast.ToString(v))
-			case []string:
-				l.Rel = cast.ToStringSlice(v)
-			}
+			l.Rel = parseStringOrArray(v)
 		case "height":
 			l.Height = cast.ToInt(v)
 		case "width":
@@ -162,42 +168,80 @@ func parseLink(data any) *Link {
 		case "templated":
 			l.Templated = cast.ToBool(v)
 		case "properties":
-			p := Properties{}
-			infoProp := cast.ToStringMap(v)
-			for kp, vp := range infoProp {
-				switch kp {
-				case "numberOfItems":
-					p.NumberOfItems = cast.ToInt(vp)
-				case "indirectAcquisition":
-					infoIndir := cast.ToSlice(vp)
-					for _, in := range infoIndir {
-						indir := parseIndirectAcquisition(in)
-						p.IndirectAcquisition = append(p.IndirectAcquisition, indir)
-					}
-				case "price":
-					pr := Price{}
-					infoPrice := cast.ToStringMap(vp)
-					for kpr, vpr := range infoPrice {
-						switch kpr {
-						case "currency":
-							pr.Currency = cast.ToString(vpr)
-						case "value":
-							pr.Value = cast.ToFloat64(vpr)
-						}
-					}
-					p.Price = &pr
-				}
-			}
-			l.Properties = &p
+			l.Properties = parseProperties(v)
 		case "children":
-			lc := parseLink(v)
-			l.Children = append(l.Children, lc)
+			l.Children = parseLinks(v)
 		}
 	}
 
 	return &l
 }
 
+func parseProperties(data any) *Properties {
+	p := Properties{}
+	info := cast.ToStringMap(data)
+	for kp, vp := range info {
+		switch kp {
+		case "numberOfItems":
+			p.NumberOfItems = cast.ToInt(vp)
+		case "provenance":
+			p.Provenance = cast.ToString(vp)
+		case "lcp_hashed_passphrase":
+			p.LCPHashedPassphrase = cast.ToString(vp)
+		case "encrypted":
+			info := cast.ToStringMap(vp)
+			p.Encrypted = &Encrypted{
+				Scheme:    cast.ToString(info["scheme"]),
+				Profile:   cast.ToString(info["profile"]),
+				Algorithm: cast.ToString(info["algorithm"]),
+			}
+		case "availability":
+			info := cast.ToStringMap(vp)
+			p.Availability = &Availability{
+				State: cast.ToString(info["state"]),
+				Since: parseOptionalDate(info["since"]),
+				Until: parseOptionalDate(info["until"]),
+			}
+		case "holds":
+			info := cast.ToStringMap(vp)
+			p.Holds = &Holds{Total: cast.ToInt(info["total"]), Position: cast.ToInt(info["position"])}
+		case "copies":
+			info := cast.ToStringMap(vp)
+			p.Copies = &Copies{Total: cast.ToInt(info["total"]), Available: cast.ToInt(info["available"])}
+		case "indirectAcquisition":
+			infoIndir := cast.ToSlice(vp)
+			for _, in := range infoIndir {
+				indir := parseIndirectAcquisition(in)
+				p.IndirectAcquisition = append(p.IndirectAcquisition, indir)
+			}
+		case "price":
+			switch vp.(type) {
+			case []any:
+				for _, vpr := range cast.ToSlice(vp) {
+					p.Price = append(p.Price, parsePrice(vpr))
+				}
+			default:
+				p.Price = Prices{parsePrice(vp)}
+			}
+		}
+	}
+	return &p
+}
+
+func parsePrice(data any) Price {
+	pr := Price{}
+	info := cast.ToStringMap(data)
+	for k, v := range info {
+		switch k {
+		case "currency":
+			pr.Currency = cast.ToString(v)
+		case "value":
+			pr.Value, _ = parseDecimal(v)
+		}
+	}
+	return pr
+}
+
 func parseIndirectAcquisition(data any) IndirectAcquisition {
 	var i IndirectAcquisition
 
@@ -221,62 +265,53 @@ func parseIndirectAcquisition(data any) IndirectAcquisition {
 func parseFacets(data any) []Facet {
 	var facets []Facet
 	info := cast.ToSlice(data)
-	f := Facet{}
 	for _, fa := range info {
-		infoA := cast.ToStringMap(fa)
-		for k, v := range infoA {
-			switch k {
-			case "metadata":
-				f.Metadata = parseMetadata(v)
-			case "links":
-				infoAL := cast.ToSlice(v)
-				for _, vA := range infoAL {
-					l := parseLink(vA)
-					f.Links = append(f.Links, l)
-				}
-			}
-		}
-		facets = append(facets, f)
+		facets = append(facets, parseFacet(fa))
 	}
 	return facets
 }
 
+func parseFacet(data any) Facet {
+	f := Facet{}
+	info := cast.ToStringMap(data)
+	for k, v := range info {
+		switch k {
+		case "metadata":
+			f.Metadata = parseMetadata(v)
+		case "links":
+			f.Links = parseLinks(v)
+		}
+	}
+	return f
+}
+
 func parseGroups(data any) []Group {
 	var groups []Group
 	info := cast.ToSlice(data)
 	for _, ga := range info {
-		g := Group{}
-		infoA := cast.ToStringMap(ga)
-		for k, v := range infoA {
-			switch k {
-			case "metadata":
-				g.Metadata = parseMetadata(v)
-			case "links":
-				infoAL := cast.ToSlice(v)
-				for _, vA := range infoAL {
-					l := parseLink(vA)
-					g.Links = append(g.Links, l)
-				}
-			case "navigation":
-				infoAN := cast.ToSlice(v)
-				for _, vAN := range infoAN {
-					l := parseLink(vAN)
-					g.Navigation = append(g.Navigation, l)
-				}
-			case "publications":
-				infoP := cast.ToSlice(v)
-				for _, vP := range infoP {
-					p := Publication{}
-					parsePublication(vP, &p)
-					g.Publications = append(g.Publications, p)
-				}
-			}
-		}
-		groups = append(groups, g)
+		groups = append(groups, parseGroup(ga))
 	}
 	return groups
 }
 
+func parseGroup(data any) Group {
+	g := Group{}
+	info := cast.ToStringMap(data)
+	for k, v := range info {
+		switch k {
+		case "metadata":
+			g.Metadata = parseMetadata(v)
+		case "links":
+			g.Links = parseLinks(v)
+		case "navigation":
+			g.Navigation = parseLinks(v)
+		case "publications":
+			g.Publications = parsePublications(v)
+		}
+	}
+	return g
+}
+
 func parsePublications(data any) []Publication {
 	var pubs []Publication
 	info := cast.ToSlice(data)
@@ -322,8 +357,12 @@ func parsePublicationMetadata(data any, metadata *PublicationMetadata) {
 		switch k {
 		case "title":
 			metadata.Title = parseMultiLanguage(v)
+		case "sort_as", "sortAs":
+			metadata.SortAs = cast.ToString(v)
 		case "identifier":
 			metadata.Identifier = cast.ToString(v)
+		case "altIdentifier":
+			metadata.AltIdentifier = parseAltIdentifiers(v)
 		case "@type":
 			metadata.RDFType = cast.ToString(v)
 		case "modified":
@@ -357,14 +396,7 @@ func parsePublicationMetadata(data any, metadata *PublicationMetadata) {
 		case "imprint":
 			metadata.Imprint = parseContributors(v)
 		case "language":
-			switch vb := v.(type) {
-			case string:
-				metadata.Language = append(metadata.Language, vb)
-			case []any:
-				for _, colls := range cast.ToStringSlice(vb) {
-					metadata.Language = append(metadata.Language, colls)
-				}
-			}
+			metadata.Language = parseStringOrArray(v)
 		case "published":
 			metadata.PublicationDate = parseDate(v)
 		case "description":
@@ -376,23 +408,27 @@ func parsePublicationMetadata(data any, metadata *PublicationMetadata) {
 		case "subject":
 			metadata.Subject = parseSubjects(v)
 		case "belongs_to", "belongsTo":
-			belong := BelongsTo{}
-			infoB := cast.ToStringMap(v)
-			for kb, vb := range infoB {
-				switch kb {
-				case "series":
-					belong.Series = parseCollections(vb)
-				case "collection":
-					belong.Collection = parseCollections(vb)
-				}
-			}
-			metadata.BelongsTo = &belong
+			metadata.BelongsTo = parseBelongsTo(v)
 		case "duration":
 			metadata.Duration = cast.ToInt(v)
 		}
 	}
 }
 
+func parseBelongsTo(data any) *BelongsTo {
+	belong := BelongsTo{}
+	info := cast.ToStringMap(data)
+	for k, v := range info {
+		switch k {
+		case "series":
+			belong.Series = parseCollections(v)
+		case "collection":
+			belong.Collection = parseCollections(v)
+		}
+	}
+	return &belong
+}
+
 func parseSubject(data any) *Subject {
 	c := &Subject{}
 	switch d := data.(type) {
@@ -404,12 +440,19 @@ func parseSubject(data any) *Subject {
 			switch ks {
 			case "name":
 				c.Name = cast.ToString(vs)
-			case "sort_as":
+			case "sort_as", "sortAs":
 				c.SortAs = cast.ToString(vs)
 			case "scheme":
 				c.Scheme = cast.ToString(vs)
 			case "code":
 				c.Code = cast.ToString(vs)
+			case "links":
+				switch vs.(type) {
+				case []any:
+					c.Links = parseLinks(vs)
+				default:
+					c.Links = append(c.Links, parseLink(vs))
+				}
 			}
 		}
 	}
@@ -493,14 +536,33 @@ func parseMultiLanguage(data any) MultiLanguage {
 	return lang
 }
 
+func parseStringOrArray(data any) StringOrArray {
+	switch d := data.(type) {
+	case string:
+		return StringOrArray{d}
+	case []any, []string:
+		return cast.ToStringSlice(d)
+	}
+	return nil
+}
+
 func parseDate(data any) *time.Time {
 	t, err := time.Parse(time.RFC3339, cast.ToString(data))
-	if err == nil {
+	if err != nil {
 		t = time.Now()
 	}
 	return &t
 }
 
+// parseOptionalDate return nil when data is not a RFC 3339 date
+func parseOptionalDate(data any) *time.Time {
+	t, err := time.Parse(time.RFC3339, cast.ToString(data))
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
 func parseContributor(data any) *Contributor {
 	switch d := data.(type) {
 	case string:
@@ -515,13 +577,19 @@ func parseContributor(data any) *Contributor {
 				c.Name = parseMultiLanguage(v)
 			case "identifier":
 				c.Identifier = cast.ToString(v)
-			case "sort_as":
+			case "sort_as", "sortAs":
 				c.SortAs = cast.ToString(v)
 			case "role":
-				c.Role = cast.ToString(v)
+				c.Role = parseStringOrArray(v)
+			case "position":
+				c.Position = cast.ToFloat64(v)
 			case "links":
-				l := parseLink(v)
-				c.Links = append(c.Links, l)
+				switch v.(type) {
+				case []any:
+					c.Links = parseLinks(v)
+				default:
+					c.Links = append(c.Links, parseLink(v))
+				}
 			}
 		}
 		return c
@@ -540,7 +608,9 @@ func parseContributors(data any) Contributors {
 		cons = append(cons, c)
 	case []any:
 		for _, con := range d {
-			cons = append(cons, parseContributor(con))
+			if isContributor(con) {
+				cons = append(cons, parseContributor(con))
+			}
 		}
 	case []map[string]any:
 		for _, con := range d {
@@ -549,3 +619,22 @@ func parseContributors(data any) Contributors {
 	}
 	return cons
 }
+
+// parseAltIdentifiers read alternate identifiers given as strings or as
+// objects with a value
+func parseAltIdentifiers(data any) StringOrArray {
+	var ids StringOrArray
+	list, ok := data.([]any)
+	if !ok {
+		list = []any{data}
+	}
+	for _, v := range list {
+		if m, ok := v.(map[string]any); ok {
+			v = m["value"]
+		}
+		if id := strings.TrimSpace(cast.ToString(v)); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
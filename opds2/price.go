@@ -0,0 +1,291 @@
+package opds2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/currency"
+)
+
+// Price price information
+type Price struct {
+	Currency string  `json:"currency"`
+	Value    Decimal `json:"value"`
+}
+
+// Prices of a link, one per currency. A single price is marshalled as an
+// object like in the OPDS 2.0 specification, several as an array.
+type Prices []Price
+
+// Decimal is an exact decimal amount, prices are kept as written instead of
+// the nearest float64
+type Decimal struct {
+	unscaled int64
+	scale    int32
+}
+
+// maxDigits is the number of digits an int64 always holds
+const maxDigits = 18
+
+// NewPrice return a validated price, the currency is an ISO 4217 code and
+// the value a decimal number like "9.99"
+func NewPrice(currencyCode string, value string) (Price, error) {
+	d, err := ParseDecimal(value)
+	if err != nil {
+		return Price{}, err
+	}
+	p := Price{Currency: strings.ToUpper(strings.TrimSpace(currencyCode)), Value: d}
+	return p, p.Validate()
+}
+
+// Validate check that the currency is an ISO 4217 code and the value isn't
+// negative
+func (p Price) Validate() error {
+	if _, err := currency.ParseISO(p.Currency); err != nil || len(p.Currency) != 3 {
+		return fmt.Errorf("opds2: invalid currency %q", p.Currency)
+	}
+	if p.Value.Sign() < 0 {
+		return fmt.Errorf("opds2: negative price %s", p.Value)
+	}
+	return nil
+}
+
+// String return the value with the standard digits of the currency and the
+// currency code, like "9.90 USD" or "1000 JPY"
+func (p Price) String() string {
+	places := int32(2)
+	if unit, err := currency.ParseISO(p.Currency); err == nil {
+		scale, _ := currency.Standard.Rounding(unit)
+		places = int32(scale)
+	}
+	return strings.TrimSpace(p.Value.StringFixed(places) + " " + p.Currency)
+}
+
+// Preferred return the price in the first of the currencies the link is
+// sold in, the first price when none match and nil when there is no price
+func (prices Prices) Preferred(currencies ...string) *Price {
+	for _, c := range currencies {
+		for i := range prices {
+			if strings.EqualFold(prices[i].Currency, c) {
+				return &prices[i]
+			}
+		}
+	}
+	if len(prices) > 0 {
+		return &prices[0]
+	}
+	return nil
+}
+
+// Currencies return the currency codes of the prices
+func (prices Prices) Currencies() []string {
+	var codes []string
+	for _, p := range prices {
+		codes = append(codes, p.Currency)
+	}
+	return codes
+}
+
+// IsFree check if there is no price or every price is zero
+func (prices Prices) IsFree() bool {
+	for _, p := range prices {
+		if !p.Value.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON write a single price as an object and several as an array
+func (prices Prices) MarshalJSON() ([]byte, error) {
+	if len(prices) == 1 {
+		return json.Marshal(prices[0])
+	}
+	return json.Marshal([]Price(prices))
+}
+
+// UnmarshalJSON read a price object or an array of prices
+func (prices *Prices) UnmarshalJSON(data []byte) error {
+	var list []Price
+	if err := json.Unmarshal(data, &list); err == nil {
+		*prices = list
+		return nil
+	}
+	var p Price
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*prices = Prices{p}
+	return nil
+}
+
+// ParseDecimal read a decimal number like "12", "-0.5" or "9.99"
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 {
+		return Decimal{}, fmt.Errorf("opds2: invalid decimal %q", s)
+	}
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	all := strings.TrimLeft(intPart+fracPart, "0")
+	if intPart+fracPart == "" || strings.Trim(intPart+fracPart, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("opds2: invalid decimal %q", s)
+	}
+	if len(all) > maxDigits {
+		return Decimal{}, fmt.Errorf("opds2: decimal %q out of range", s)
+	}
+	var n int64
+	if all != "" {
+		n, _ = strconv.ParseInt(all, 10, 64)
+	}
+	if strings.HasPrefix(s, "-") {
+		n = -n
+	}
+	return Decimal{unscaled: n, scale: int32(len(fracPart))}, nil
+}
+
+// NewDecimal return the decimal unscaled × 10^-scale, NewDecimal(999, 2)
+// is 9.99
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	return Decimal{unscaled: unscaled, scale: scale}
+}
+
+// DecimalFromFloat return the shortest decimal that read back as f, the
+// number written in a JSON document for values parsed as float64
+func DecimalFromFloat(f float64) Decimal {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}
+	}
+	d, err := ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		r, _ := ParseDecimal(strconv.FormatFloat(f, 'f', 2, 64))
+		return r
+	}
+	return d
+}
+
+// Sign return -1, 0 or 1 as the decimal is negative, zero or positive
+func (d Decimal) Sign() int {
+	switch {
+	case d.unscaled < 0:
+		return -1
+	case d.unscaled > 0:
+		return 1
+	}
+	return 0
+}
+
+// IsZero check if the decimal is zero
+func (d Decimal) IsZero() bool {
+	return d.unscaled == 0
+}
+
+// Cmp compare the decimals and return -1, 0 or 1 as d is less than, equal
+// to or greater than o
+func (d Decimal) Cmp(o Decimal) int {
+	return d.rat().Cmp(o.rat())
+}
+
+// Float64 return the nearest float64, for display and statistics only
+func (d Decimal) Float64() float64 {
+	f, _ := d.rat().Float64()
+	return f
+}
+
+func (d Decimal) rat() *big.Rat {
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.scale)), nil)
+	return new(big.Rat).SetFrac(big.NewInt(d.unscaled), denom)
+}
+
+// String return the decimal with its own number of decimal places
+func (d Decimal) String() string {
+	return d.StringFixed(d.scale)
+}
+
+// StringFixed return the decimal with places decimal places, rounded half
+// away from zero
+func (d Decimal) StringFixed(places int32) string {
+	if places < 0 {
+		places = 0
+	}
+	r := d.rat()
+	return r.FloatString(int(places))
+}
+
+// MarshalJSON write the decimal as a JSON number
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON read a JSON number or a string holding a number
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+	v, err := ParseDecimal(s)
+	if err != nil {
+		f, errFloat := strconv.ParseFloat(s, 64)
+		if errFloat != nil {
+			return err
+		}
+		v = DecimalFromFloat(f)
+	}
+	*d = v
+	return nil
+}
+
+// MarshalText write the decimal, for XML
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText read a decimal, for XML
+func (d *Decimal) UnmarshalText(text []byte) error {
+	if strings.TrimSpace(string(text)) == "" {
+		*d = Decimal{}
+		return nil
+	}
+	v, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalBinary write the decimal, for gob
+func (d Decimal) MarshalBinary() ([]byte, error) {
+	return d.MarshalText()
+}
+
+// UnmarshalBinary read a decimal, for gob
+func (d *Decimal) UnmarshalBinary(data []byte) error {
+	return d.UnmarshalText(data)
+}
+
+// parseDecimal read a decimal from a parsed JSON value
+func parseDecimal(data any) (Decimal, error) {
+	switch v := data.(type) {
+	case string:
+		return ParseDecimal(v)
+	case json.Number:
+		return ParseDecimal(v.String())
+	case float64:
+		return DecimalFromFloat(v), nil
+	case float32:
+		return DecimalFromFloat(float64(v)), nil
+	case int:
+		return NewDecimal(int64(v), 0), nil
+	case int64:
+		return NewDecimal(v, 0), nil
+	case nil:
+		return Decimal{}, nil
+	}
+	return Decimal{}, errors.New("opds2: invalid decimal")
+}
@@ -2,6 +2,8 @@ package opds2
 
 import (
 	"time"
+
+	"github.com/ohzqq/libopds2-go/rel"
 )
 
 // Publication is a collection for a given publication
@@ -15,7 +17,9 @@ type Publication struct {
 type PublicationMetadata struct {
 	RDFType         string        `json:"@type,omitempty"` //Defaults to schema.org for EBook
 	Title           MultiLanguage `json:"title"`
+	SortAs          string        `json:"sort_as,omitempty"`
 	Identifier      string        `json:"identifier"`
+	AltIdentifier   StringOrArray `json:"altIdentifier,omitempty"`
 	Author          Contributors  `json:"author,omitempty"`
 	Translator      Contributors  `json:"translator,omitempty"`
 	Editor          Contributors  `json:"editor,omitempty"`
@@ -90,6 +94,9 @@ func (publication *Publication) BelongsToSeries(data any) *Collection {
 }
 
 func (publication *Publication) BelongsToCollection(data any) *Collection {
+	if publication.Metadata.BelongsTo == nil {
+		publication.Metadata.BelongsTo = &BelongsTo{}
+	}
 	col := parseCollection(data)
 	publication.Metadata.BelongsTo.Collection = append(publication.Metadata.BelongsTo.Collection, col)
 	return col
@@ -106,3 +113,31 @@ func (publication *Publication) FindFirstLinkByRel(rel string) *Link {
 func (publication *Publication) FindFirstLinkByType(mt string) *Link {
 	return publication.Links.FindFirstLinkByType(mt)
 }
+
+// IsAvailableNow check if one of the acquisition links of the publication
+// can be acquired right now
+func (publication *Publication) IsAvailableNow() bool {
+	now := time.Now()
+	for _, l := range publication.Links.Acquisitions() {
+		if l.IsAvailable(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// BorrowLink return the first borrow link available right now, the first
+// borrow link when none are available
+func (publication *Publication) BorrowLink() *Link {
+	borrow := publication.Links.FilterByRel(rel.AcquisitionBorrow)
+	now := time.Now()
+	for _, l := range borrow {
+		if l.IsAvailable(now) {
+			return l
+		}
+	}
+	if len(borrow) > 0 {
+		return borrow[0]
+	}
+	return &Link{}
+}
@@ -0,0 +1,381 @@
+package opds2
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// SortKey select the order of the publications returned by a Query
+type SortKey int
+
+// Sort orders of a Query, SortByNone keep the order of the feed
+const (
+	SortByNone SortKey = iota
+	SortByTitle
+	SortByAuthor
+	SortByPublished
+	SortByModified
+	SortBySeries
+)
+
+// Query filter and sort the publications of a feed, including the ones in
+// groups, build it with Feed.Query and chain the filters
+type Query struct {
+	feed    *Feed
+	filters []func(*Publication) bool
+	sortBy  SortKey
+	series  string
+	desc    bool
+	limit   int
+	page    int
+}
+
+// Query start a query over the publications of the feed and its groups
+func (feed *Feed) Query() *Query {
+	return &Query{feed: feed}
+}
+
+// Where keep the publications matching fn
+func (q *Query) Where(fn func(*Publication) bool) *Query {
+	q.filters = append(q.filters, fn)
+	return q
+}
+
+// ByAuthor keep the publications with an author matching name, the name or
+// the sort_as of the author is compared ignoring case
+func (q *Query) ByAuthor(name string) *Query {
+	return q.Where(func(p *Publication) bool {
+		for _, a := range p.Metadata.Author {
+			if matchContributor(a, name) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// InSeries keep the publications of the series, SortBySeries then order them
+// by position, the unknown positions last
+func (q *Query) InSeries(name string) *Query {
+	q.series = name
+	return q.Where(func(p *Publication) bool {
+		_, ok := seriesPosition(p, name)
+		return ok
+	})
+}
+
+// Language keep the publications in lang, "fr" match "fr-CA"
+func (q *Query) Language(lang string) *Query {
+	return q.Where(func(p *Publication) bool {
+		for _, l := range p.Metadata.Language {
+			if strings.EqualFold(l, lang) || strings.HasPrefix(strings.ToLower(l), strings.ToLower(lang)+"-") {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// HasFormat keep the publications with an acquisition link for the media
+// type, directly or through an indirect acquisition, media types are
+// compared with mediatype.Matches so audio/* match any audio format
+func (q *Query) HasFormat(mediaType string) *Query {
+	return q.Where(func(p *Publication) bool {
+		for _, l := range p.Links {
+			if !l.IsAcquisition() {
+				continue
+			}
+			if mediatype.Matches(mediaType, l.TypeLink) {
+				return true
+			}
+			if l.Properties != nil && hasIndirectType(l.Properties.IndirectAcquisition, mediaType) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// PublishedAfter keep the publications published after t
+func (q *Query) PublishedAfter(t time.Time) *Query {
+	return q.Where(func(p *Publication) bool {
+		return p.Metadata.PublicationDate != nil && p.Metadata.PublicationDate.After(t)
+	})
+}
+
+// PublishedBefore keep the publications published before t
+func (q *Query) PublishedBefore(t time.Time) *Query {
+	return q.Where(func(p *Publication) bool {
+		return p.Metadata.PublicationDate != nil && p.Metadata.PublicationDate.Before(t)
+	})
+}
+
+// SortBy order the publications by key
+func (q *Query) SortBy(key SortKey) *Query {
+	q.sortBy = key
+	return q
+}
+
+// Descending reverse the sort order
+func (q *Query) Descending() *Query {
+	q.desc = true
+	return q
+}
+
+// Limit set the number of publications per page
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Page select the page to return, starting at 1, when a limit is set
+func (q *Query) Page(n int) *Query {
+	q.page = n
+	return q
+}
+
+// All return every matching publication, sorted but not paginated
+func (q *Query) All() []Publication {
+	var pubs []Publication
+	seen := make(map[string]bool)
+	add := func(list []Publication) {
+		for i := range list {
+			p := &list[i]
+			if k := NormalizeIdentifier(PublicationKey(p)); k != "" {
+				if seen[k] {
+					continue
+				}
+				seen[k] = true
+			}
+			if q.match(p) {
+				pubs = append(pubs, *p)
+			}
+		}
+	}
+	add(q.feed.Publications)
+	for _, g := range q.feed.Groups {
+		add(g.Publications)
+	}
+
+	q.sort(pubs)
+	return pubs
+}
+
+// Publications return the matching publications of the selected page
+func (q *Query) Publications() []Publication {
+	pubs := q.All()
+	start, end := q.bounds(len(pubs))
+	return pubs[start:end]
+}
+
+// Feed return a new feed holding the selected page of publications, the
+// links and facets of the feed are kept and the pagination metadata is
+// recomputed, pagination links are dropped
+func (q *Query) Feed() Feed {
+	pubs := q.All()
+	start, end := q.bounds(len(pubs))
+
+	feed := Feed{
+		Context:  q.feed.Context,
+		Metadata: q.feed.Metadata,
+		Facets:   q.feed.Facets,
+	}
+	feed.Links = Links{}
+	for _, l := range q.feed.Links {
+		if !isPaginationLink(l) {
+			feed.Links = append(feed.Links, l)
+		}
+	}
+	feed.Publications = pubs[start:end]
+	feed.Metadata.NumberOfItems = len(pubs)
+	feed.Metadata.ItemsPerPage = 0
+	feed.Metadata.CurrentPage = 0
+	if q.limit > 0 {
+		feed.Metadata.ItemsPerPage = q.limit
+		feed.Metadata.CurrentPage = q.currentPage()
+	}
+	return feed
+}
+
+func (q *Query) match(p *Publication) bool {
+	for _, fn := range q.filters {
+		if !fn(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *Query) currentPage() int {
+	if q.page < 1 {
+		return 1
+	}
+	return q.page
+}
+
+func (q *Query) bounds(n int) (int, int) {
+	if q.limit <= 0 {
+		return 0, n
+	}
+	start := (q.currentPage() - 1) * q.limit
+	if start > n {
+		start = n
+	}
+	end := start + q.limit
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+func (q *Query) sort(pubs []Publication) {
+	var less func(a, b *Publication) bool
+	switch q.sortBy {
+	case SortByTitle:
+		less = func(a, b *Publication) bool {
+			return titleSortKey(a) < titleSortKey(b)
+		}
+	case SortByAuthor:
+		less = func(a, b *Publication) bool {
+			ka, kb := authorSortKey(a), authorSortKey(b)
+			if ka != kb {
+				return ka < kb
+			}
+			return titleSortKey(a) < titleSortKey(b)
+		}
+	case SortByPublished:
+		less = func(a, b *Publication) bool {
+			return timeBefore(a.Metadata.PublicationDate, b.Metadata.PublicationDate)
+		}
+	case SortByModified:
+		less = func(a, b *Publication) bool {
+			return timeBefore(a.Metadata.Modified, b.Metadata.Modified)
+		}
+	case SortBySeries:
+		less = func(a, b *Publication) bool {
+			sa, pa := seriesSortKey(a, q.series)
+			sb, pb := seriesSortKey(b, q.series)
+			if sa != sb {
+				return sa < sb
+			}
+			if pa != pb {
+				return LessSeriesPosition(pa, pb)
+			}
+			return titleSortKey(a) < titleSortKey(b)
+		}
+	default:
+		if q.desc {
+			for i, j := 0, len(pubs)-1; i < j; i, j = i+1, j-1 {
+				pubs[i], pubs[j] = pubs[j], pubs[i]
+			}
+		}
+		return
+	}
+
+	sort.SliceStable(pubs, func(i, j int) bool {
+		if q.desc {
+			return less(&pubs[j], &pubs[i])
+		}
+		return less(&pubs[i], &pubs[j])
+	})
+}
+
+func titleSortKey(p *Publication) string {
+	if p.Metadata.SortAs != "" {
+		return strings.ToLower(p.Metadata.SortAs)
+	}
+	return strings.ToLower(p.Metadata.Title.String())
+}
+
+func authorSortKey(p *Publication) string {
+	if len(p.Metadata.Author) == 0 {
+		return ""
+	}
+	lang := ""
+	if len(p.Metadata.Language) > 0 {
+		lang = p.Metadata.Language[0]
+	}
+	return strings.ToLower(p.Metadata.Author[0].SortKey(lang))
+}
+
+// seriesSortKey return the series name and position, the series of the
+// query is used when set
+func seriesSortKey(p *Publication, name string) (string, float64) {
+	if name != "" {
+		pos, _ := seriesPosition(p, name)
+		return strings.ToLower(name), pos
+	}
+	if p.Metadata.BelongsTo == nil || len(p.Metadata.BelongsTo.Series) == 0 {
+		return "", 0
+	}
+	s := p.Metadata.BelongsTo.Series[0]
+	if s.Contributor == nil {
+		return "", s.Position
+	}
+	if s.SortAs != "" {
+		return strings.ToLower(s.SortAs), s.Position
+	}
+	return strings.ToLower(s.Name.String()), s.Position
+}
+
+func seriesPosition(p *Publication, name string) (float64, bool) {
+	if p.Metadata.BelongsTo == nil {
+		return 0, false
+	}
+	for _, s := range p.Metadata.BelongsTo.Series {
+		if s.Contributor != nil && matchContributor(s.Contributor, name) {
+			return s.Position, true
+		}
+	}
+	return 0, false
+}
+
+func matchContributor(c *Contributor, name string) bool {
+	if strings.EqualFold(c.SortAs, name) {
+		return true
+	}
+	if strings.EqualFold(c.Name.SingleString, name) {
+		return true
+	}
+	for _, n := range c.Name.MultiString {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// timeBefore order missing dates last
+func timeBefore(a, b *time.Time) bool {
+	switch {
+	case a == nil:
+		return false
+	case b == nil:
+		return true
+	}
+	return a.Before(*b)
+}
+
+// isPaginationLink check if l is a first, previous, next or last link
+func isPaginationLink(l *Link) bool {
+	for _, r := range l.Rel {
+		switch rel.Normalize(r) {
+		case rel.First, rel.Previous, rel.Next, rel.Last:
+			return true
+		}
+	}
+	return false
+}
+
+func hasIndirectType(ias []IndirectAcquisition, mediaType string) bool {
+	for _, ia := range ias {
+		if mediatype.Matches(mediaType, ia.TypeAcquisition) || hasIndirectType(ia.Child, mediaType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package opds2
+
+import "strings"
+
+// Roles of the contributors stored in their own list of PublicationMetadata,
+// any other role is kept in the roles of the contributors of Contributor
+const (
+	RoleAuthor      = "author"
+	RoleTranslator  = "translator"
+	RoleEditor      = "editor"
+	RoleArtist      = "artist"
+	RoleIllustrator = "illustrator"
+	RoleLetterer    = "letterer"
+	RolePenciler    = "penciler"
+	RoleColorist    = "colorist"
+	RoleInker       = "inker"
+	RoleNarrator    = "narrator"
+	RoleContributor = "contributor"
+	RolePublisher   = "publisher"
+	RoleImprint     = "imprint"
+)
+
+// relatorTerms map MARC relator codes to their term, the roles with a list
+// in PublicationMetadata use its name
+var relatorTerms = map[string]string{
+	"abr": "abridger",
+	"adp": "adapter",
+	"aft": "author of afterword, colophon, etc.",
+	"ann": "annotator",
+	"ant": "bibliographic antecedent",
+	"art": RoleArtist,
+	"aui": "author of introduction, etc.",
+	"aut": RoleAuthor,
+	"bkd": "book designer",
+	"clr": RoleColorist,
+	"cmm": "commentator",
+	"cmp": "composer",
+	"com": "compiler",
+	"cov": "cover designer",
+	"cre": "creator",
+	"ctb": RoleContributor,
+	"ctg": "cartographer",
+	"cwt": "commentator for written text",
+	"dsr": "designer",
+	"edc": "editor of compilation",
+	"edt": RoleEditor,
+	"ill": RoleIllustrator,
+	"isb": "issuing body",
+	"ive": "interviewee",
+	"ivr": "interviewer",
+	"lyr": "lyricist",
+	"mus": "musician",
+	"nrt": RoleNarrator,
+	"oth": "other",
+	"pbl": RolePublisher,
+	"pht": "photographer",
+	"prf": "performer",
+	"pro": "producer",
+	"prt": "printer",
+	"sce": "scenarist",
+	"spk": "speaker",
+	"trl": RoleTranslator,
+	"wac": "writer of added commentary",
+	"wat": "writer of added text",
+	"win": "writer of introduction",
+	"wpr": "writer of preface",
+	"wst": "writer of supplementary textual content",
+}
+
+// RelatorTerm return the term of a MARC relator code like "aut" or "trl",
+// empty for an unknown code
+func RelatorTerm(code string) string {
+	return relatorTerms[strings.ToLower(strings.TrimSpace(code))]
+}
+
+// RelatorCode return the MARC relator code of a role or a relator term,
+// empty when the role has no code like the comics roles
+func RelatorCode(role string) string {
+	role = NormalizeRole(role)
+	for code, term := range relatorTerms {
+		if term == role {
+			return code
+		}
+	}
+	return ""
+}
+
+// NormalizeRole return the role for a MARC relator code or a role name,
+// lowercased, "aut" and "Author" are both "author"
+func NormalizeRole(role string) string {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if term, ok := relatorTerms[role]; ok {
+		return term
+	}
+	if role == "penciller" {
+		return RolePenciler
+	}
+	return role
+}
+
+// roleList return the list of the metadata holding the contributors with
+// role, nil for the roles kept in Contributor
+func (m *PublicationMetadata) roleList(role string) *Contributors {
+	switch role {
+	case RoleAuthor:
+		return &m.Author
+	case RoleTranslator:
+		return &m.Translator
+	case RoleEditor:
+		return &m.Editor
+	case RoleArtist:
+		return &m.Artist
+	case RoleIllustrator:
+		return &m.Illustrator
+	case RoleLetterer:
+		return &m.Letterer
+	case RolePenciler:
+		return &m.Penciler
+	case RoleColorist:
+		return &m.Colorist
+	case RoleInker:
+		return &m.Inker
+	case RoleNarrator:
+		return &m.Narrator
+	case RoleContributor:
+		return &m.Contributor
+	case RolePublisher:
+		return &m.Publisher
+	case RoleImprint:
+		return &m.Imprint
+	}
+	return nil
+}
+
+// IsMetadataRole check if role, a role name or a MARC relator code, has its
+// own list of contributors in PublicationMetadata
+func IsMetadataRole(role string) bool {
+	return (&PublicationMetadata{}).roleList(NormalizeRole(role)) != nil
+}
+
+// AddContributor add c with role, a role name or a MARC relator code, to
+// the list of the metadata for the role, the other roles are added as given
+// to the roles of c in Contributor, where a contributor already listed with the
+// same name get the role instead
+func (m *PublicationMetadata) AddContributor(role string, c *Contributor) {
+	role = strings.TrimSpace(role)
+	if list := m.roleList(NormalizeRole(role)); list != nil {
+		*list = append(*list, c)
+		return
+	}
+	if role == "" {
+		m.Contributor = append(m.Contributor, c)
+		return
+	}
+	for _, other := range m.Contributor {
+		if other.Name.String() == c.Name.String() && len(other.Role) > 0 {
+			other.AddRole(role)
+			return
+		}
+	}
+	c.AddRole(role)
+	m.Contributor = append(m.Contributor, c)
+}
+
+// Contributors return the contributors with role, a role name or a MARC
+// relator code, those of its list and those of Contributor having the role
+func (m *PublicationMetadata) Contributors(role string) Contributors {
+	role = NormalizeRole(role)
+	var cons Contributors
+	if list := m.roleList(role); list != nil && role != RoleContributor {
+		cons = append(cons, *list...)
+	}
+	for _, c := range m.Contributor {
+		if c.HasRole(role) || (role == RoleContributor && len(c.Role) == 0) {
+			cons = append(cons, c)
+		}
+	}
+	return cons
+}
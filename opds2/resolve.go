@@ -0,0 +1,208 @@
+package opds2
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/ohzqq/libopds2-go/rel"
+)
+
+// ResolveLinks rewrite every href of the feed as an absolute URL against
+// BaseURL, or the self link when BaseURL is empty
+func (feed *Feed) ResolveLinks() error {
+	base, err := feed.base()
+	if err != nil {
+		return err
+	}
+	feed.walkLinks(func(l *Link) {
+		l.Resolve(base)
+	})
+	return nil
+}
+
+// RelativizeLinks rewrite the hrefs on the host of BaseURL, or of the self
+// link when BaseURL is empty, relative to it so that the feed can be moved
+// with the resources it links to
+func (feed *Feed) RelativizeLinks() error {
+	base, err := feed.base()
+	if err != nil {
+		return err
+	}
+	feed.walkLinks(func(l *Link) {
+		l.Relativize(base)
+	})
+	return nil
+}
+
+// ResolveLinks rewrite every href of the publication as an absolute URL
+// against base
+func (publication *Publication) ResolveLinks(base *url.URL) {
+	publication.walkLinks(func(l *Link) {
+		l.Resolve(base)
+	})
+}
+
+// RelativizeLinks rewrite the hrefs of the publication relative to base
+func (publication *Publication) RelativizeLinks(base *url.URL) {
+	publication.walkLinks(func(l *Link) {
+		l.Relativize(base)
+	})
+}
+
+// Resolve rewrite the href of the link and its children as absolute URLs
+// against base, the variables of templated links are kept as is
+func (l *Link) Resolve(base *url.URL) {
+	l.Href = resolveHref(base, l.Href)
+	for _, c := range l.Children {
+		c.Resolve(base)
+	}
+}
+
+// Relativize rewrite the href of the link and its children relative to
+// base when they are on the same host
+func (l *Link) Relativize(base *url.URL) {
+	l.Href = relativeHref(base, l.Href)
+	for _, c := range l.Children {
+		c.Relativize(base)
+	}
+}
+
+func (feed *Feed) base() (*url.URL, error) {
+	base := feed.BaseURL
+	if base == "" {
+		base = feed.Links.FindFirstLinkByRel(rel.Self).Href
+	}
+	if base == "" {
+		return nil, errors.New("opds2: feed has no base URL")
+	}
+	return url.Parse(base)
+}
+
+// walkLinks call fn on every link of the feed, its facets, groups and
+// publications
+func (feed *Feed) walkLinks(fn func(*Link)) {
+	each := func(links Links) {
+		for _, l := range links {
+			fn(l)
+		}
+	}
+	each(feed.Links)
+	each(feed.Navigation)
+	for _, f := range feed.Facets {
+		each(f.Links)
+	}
+	for i := range feed.Publications {
+		feed.Publications[i].walkLinks(fn)
+	}
+	for _, g := range feed.Groups {
+		each(g.Links)
+		each(g.Navigation)
+		for i := range g.Publications {
+			g.Publications[i].walkLinks(fn)
+		}
+	}
+}
+
+// walkLinks call fn on the links, images, contributor and subject links of
+// the publication
+func (publication *Publication) walkLinks(fn func(*Link)) {
+	each := func(links Links) {
+		for _, l := range links {
+			fn(l)
+		}
+	}
+	each(publication.Links)
+	each(publication.Images)
+	m := &publication.Metadata
+	for _, cons := range m.contributors() {
+		for _, c := range *cons {
+			each(c.Links)
+		}
+	}
+	for _, s := range m.Subject {
+		each(s.Links)
+	}
+	if m.BelongsTo != nil {
+		for _, cols := range []Collections{m.BelongsTo.Series, m.BelongsTo.Collection} {
+			for _, c := range cols {
+				if c.Contributor != nil {
+					each(c.Links)
+				}
+			}
+		}
+	}
+}
+
+// splitTemplate split a templated href before its first variable
+func splitTemplate(href string) (string, string) {
+	if i := strings.Index(href, "{"); i >= 0 {
+		return href[:i], href[i:]
+	}
+	return href, ""
+}
+
+func resolveHref(base *url.URL, href string) string {
+	if href == "" {
+		return href
+	}
+	prefix, template := splitTemplate(href)
+	u, err := url.Parse(prefix)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(u).String() + template
+}
+
+// relativeHref return href relative to base, it is unchanged when it is
+// already relative or on another host
+func relativeHref(base *url.URL, href string) string {
+	prefix, template := splitTemplate(href)
+	u, err := url.Parse(prefix)
+	if err != nil || !u.IsAbs() || u.Opaque != "" {
+		return href
+	}
+	if u.Scheme != base.Scheme || u.Host != base.Host || u.User.String() != base.User.String() {
+		return href
+	}
+
+	basePath := base.EscapedPath()
+	if basePath == "" {
+		basePath = "/"
+	}
+	dir := strings.Split(basePath[:strings.LastIndex(basePath, "/")], "/")
+	target := strings.Split(u.EscapedPath(), "/")
+	if len(target) == 1 && target[0] == "" {
+		target = []string{"", ""}
+	}
+
+	common := 0
+	for common < len(dir) && common < len(target)-1 && dir[common] == target[common] {
+		common++
+	}
+	r := strings.Repeat("../", len(dir)-common) + strings.Join(target[common:], "/")
+	switch {
+	case r == "":
+		r = "./"
+	case strings.Contains(strings.SplitN(r, "/", 2)[0], ":"):
+		r = "./" + r
+	}
+
+	if u.RawQuery != "" || u.ForceQuery {
+		r += "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		r += "#" + u.EscapedFragment()
+	}
+	return r + template
+}
+
+// fileURL return the file URL of a local path
+func fileURL(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
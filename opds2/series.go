@@ -0,0 +1,206 @@
+package opds2
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+)
+
+// SeriesEntry is a publication of a series at its position, 0 when the
+// position is unknown
+type SeriesEntry struct {
+	Publication *Publication
+	Position    float64
+}
+
+// Series is the publications of a feed belonging to a series, in reading
+// order
+type Series struct {
+	Name    string
+	SortAs  string
+	Entries []SeriesEntry
+}
+
+// SeriesIndex index the publications of a feed by series, a publication
+// belonging to several series is in each of them
+type SeriesIndex struct {
+	series []*Series
+	byName map[string]*Series
+}
+
+// SeriesIndex index the series of the publications of the feed and its
+// groups
+func (feed *Feed) SeriesIndex() *SeriesIndex {
+	return NewSeriesIndex(feed.Query().All())
+}
+
+// NewSeriesIndex index the series of pubs, series are matched by name
+// ignoring case
+func NewSeriesIndex(pubs []Publication) *SeriesIndex {
+	idx := &SeriesIndex{byName: make(map[string]*Series)}
+	for i := range pubs {
+		p := &pubs[i]
+		if p.Metadata.BelongsTo == nil {
+			continue
+		}
+		for _, col := range p.Metadata.BelongsTo.Series {
+			name := strings.TrimSpace(col.String())
+			if name == "" {
+				continue
+			}
+			key := strings.ToLower(name)
+			s, ok := idx.byName[key]
+			if !ok {
+				s = &Series{Name: name}
+				idx.byName[key] = s
+				idx.series = append(idx.series, s)
+			}
+			if s.SortAs == "" && col.Contributor != nil {
+				s.SortAs = col.SortAs
+			}
+			s.Entries = append(s.Entries, SeriesEntry{Publication: p, Position: col.Position})
+		}
+	}
+	for _, s := range idx.series {
+		s.sort()
+	}
+	sort.SliceStable(idx.series, func(i, j int) bool {
+		return idx.series[i].sortKey() < idx.series[j].sortKey()
+	})
+	return idx
+}
+
+// Series return every series sorted by name
+func (idx *SeriesIndex) Series() []*Series {
+	return idx.series
+}
+
+// Get return the series named name, ignoring case
+func (idx *SeriesIndex) Get(name string) (*Series, bool) {
+	s, ok := idx.byName[strings.ToLower(strings.TrimSpace(name))]
+	return s, ok
+}
+
+// Groups return a group per series titled like "Series: X (n books)" with
+// the publications in reading order and a link to href(series), the feed
+// of the series
+func (idx *SeriesIndex) Groups(href func(*Series) string) []Group {
+	var groups []Group
+	for _, s := range idx.series {
+		g := Group{Publications: s.Publications()}
+		g.Metadata.Title.SingleString = s.Title()
+		g.Metadata.NumberOfItems = s.Len()
+		if href != nil {
+			g.Links = append(g.Links, &Link{Href: href(s), TypeLink: mediatype.OPDS2, Rel: []string{"self"}, Title: s.Name})
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// Navigation return a navigation link per series titled like
+// "Series: X (n books)" to href(series), the feed of the series
+func (idx *SeriesIndex) Navigation(href func(*Series) string) Links {
+	var links Links
+	for _, s := range idx.series {
+		links = append(links, &Link{
+			Href:       href(s),
+			TypeLink:   mediatype.OPDS2,
+			Rel:        []string{"subsection"},
+			Title:      s.Title(),
+			Properties: &Properties{NumberOfItems: s.Len()},
+		})
+	}
+	return links
+}
+
+// Len return the number of publications of the series
+func (s *Series) Len() int {
+	return len(s.Entries)
+}
+
+// Title return "Series: X (n books)"
+func (s *Series) Title() string {
+	books := "books"
+	if s.Len() == 1 {
+		books = "book"
+	}
+	return "Series: " + s.Name + " (" + strconv.Itoa(s.Len()) + " " + books + ")"
+}
+
+// Publications return the publications of the series in reading order
+func (s *Series) Publications() []Publication {
+	pubs := make([]Publication, 0, len(s.Entries))
+	for _, e := range s.Entries {
+		pubs = append(pubs, *e.Publication)
+	}
+	return pubs
+}
+
+// Gaps return the whole positions missing between 1 and the last whole
+// position of the series, fractional positions like 1.5 neither count as
+// a gap nor fill one
+func (s *Series) Gaps() []float64 {
+	have := make(map[float64]bool)
+	last := 0.0
+	for _, e := range s.Entries {
+		if e.Position > 0 && e.Position == math.Trunc(e.Position) {
+			have[e.Position] = true
+			last = math.Max(last, e.Position)
+		}
+	}
+	var gaps []float64
+	for pos := 1.0; pos < last; pos++ {
+		if !have[pos] {
+			gaps = append(gaps, pos)
+		}
+	}
+	return gaps
+}
+
+// Feed return an acquisition feed of the series in reading order, self is
+// the href of its self link
+func (s *Series) Feed(self string) Feed {
+	feed := Feed{Links: Links{}}
+	feed.Metadata.Title.SingleString = s.Name
+	feed.Metadata.NumberOfItems = s.Len()
+	if self != "" {
+		feed.AddLink(self, "self", mediatype.OPDS2, false)
+	}
+	feed.Publications = s.Publications()
+	return feed
+}
+
+// sort order the entries by position, the unknown positions last, then by
+// title
+func (s *Series) sort() {
+	sort.SliceStable(s.Entries, func(i, j int) bool {
+		a, b := s.Entries[i], s.Entries[j]
+		if a.Position != b.Position {
+			return LessSeriesPosition(a.Position, b.Position)
+		}
+		return titleSortKey(a.Publication) < titleSortKey(b.Publication)
+	})
+}
+
+func (s *Series) sortKey() string {
+	if s.SortAs != "" {
+		return strings.ToLower(s.SortAs)
+	}
+	return strings.ToLower(s.Name)
+}
+
+// LessSeriesPosition order positions in a series, 0 is an unknown position
+// coming after the others
+func LessSeriesPosition(a, b float64) bool {
+	switch {
+	case a == 0:
+		return false
+	case b == 0:
+		return true
+	}
+	return a < b
+}
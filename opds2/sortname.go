@@ -0,0 +1,141 @@
+package opds2
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/language"
+)
+
+// nameParticles are the particles before a family name, in lowercase they
+// are written after the given names, "Beethoven, Ludwig van"
+var nameParticles = map[string]bool{
+	"da": true, "das": true, "de": true, "del": true, "della": true, "den": true,
+	"der": true, "des": true, "di": true, "do": true, "dos": true, "du": true,
+	"la": true, "le": true, "lo": true, "ten": true, "ter": true, "van": true,
+	"von": true, "zu": true,
+}
+
+// surnameParticles are the particles kept with the family name even in
+// lowercase, for each language, the French articles "du Bellay, Joachim"
+// and every Italian particle "da Ponte, Lorenzo"
+var surnameParticles = map[string]func(string) bool{
+	"fr": func(p string) bool { return p == "du" || p == "des" || p == "la" || p == "le" },
+	"it": func(string) bool { return true },
+}
+
+// familyNameFirst are the languages writing the family name first
+var familyNameFirst = map[string]bool{
+	"hu": true, "ja": true, "ko": true, "zh": true,
+}
+
+// nameSuffixes are the generational and honorific suffixes written after
+// the inverted name, "King, Martin Luther, Jr."
+var nameSuffixes = map[string]bool{
+	"jr": true, "sr": true, "ii": true, "iii": true, "iv": true,
+	"phd": true, "md": true, "esq": true,
+}
+
+// SortName return the name of a person in "Last, First" order for sorting,
+// lang is the BCP 47 language of the name:
+//
+//   - lowercase particles go after the given names, "Gogh, Vincent van",
+//     capitalized ones stay with the family name, "Du Maurier, Daphne"
+//   - French articles and Italian particles stay with the family name
+//   - suffixes are kept last, "King, Martin Luther, Jr."
+//   - Hungarian, Chinese, Japanese and Korean names are family name first,
+//     "Liu, Cixin", and names in Han, kana or Hangul are unchanged
+//
+// Names already containing a comma and single words are unchanged.
+func SortName(name string, lang string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	suffix := ""
+	if i := strings.LastIndex(name, ","); i >= 0 {
+		if !isNameSuffix(name[i+1:]) {
+			return name
+		}
+		name, suffix = strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+1:])
+	}
+	words := strings.Fields(name)
+	for suffix == "" && len(words) > 2 && isNameSuffix(words[len(words)-1]) {
+		suffix = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	if len(words) < 2 || hasCJK(name) {
+		return joinSuffix(name, suffix)
+	}
+
+	base := ""
+	if t, err := language.Parse(lang); err == nil {
+		b, _ := t.Base()
+		base = b.String()
+	}
+	if familyNameFirst[base] {
+		return joinSuffix(words[0]+", "+strings.Join(words[1:], " "), suffix)
+	}
+
+	last := len(words) - 1
+	start := last
+	for start > 1 && nameParticles[strings.ToLower(words[start-1])] {
+		start--
+	}
+	// the particles from kept on belong to the family name
+	kept := last
+	for kept > start && keepParticle(words[kept-1], base) {
+		kept--
+	}
+	given := strings.Join(words[:start], " ")
+	if moved := strings.Join(words[start:kept], " "); moved != "" {
+		given += " " + moved
+	}
+	return joinSuffix(strings.Join(words[kept:], " ")+", "+given, suffix)
+}
+
+func keepParticle(p string, lang string) bool {
+	if r := []rune(p); unicode.IsUpper(r[0]) {
+		return true
+	}
+	keep, ok := surnameParticles[lang]
+	return ok && keep(p)
+}
+
+func isNameSuffix(s string) bool {
+	s = strings.ToLower(strings.Trim(strings.TrimSpace(s), "."))
+	return nameSuffixes[strings.ReplaceAll(s, ".", "")]
+}
+
+func hasCJK(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinSuffix(name string, suffix string) string {
+	if suffix == "" {
+		return name
+	}
+	return name + ", " + suffix
+}
+
+// GenerateSortAs set the SortAs of the persons contributing to the
+// publication that have none, in the first language of the publication,
+// publishers and imprints are left unchanged
+func (m *PublicationMetadata) GenerateSortAs() {
+	lang := ""
+	if len(m.Language) > 0 {
+		lang = m.Language[0]
+	}
+	for _, cons := range m.contributors() {
+		if cons == &m.Publisher || cons == &m.Imprint {
+			continue
+		}
+		for _, c := range *cons {
+			if c.SortAs == "" {
+				c.SortAs = SortName(c.Name.String(), lang)
+			}
+		}
+	}
+}
@@ -11,12 +11,27 @@ type Subject struct {
 	SortAs string `json:"sort_as,omitempty"`
 	Scheme string `json:"scheme,omitempty"`
 	Code   string `json:"code,omitempty"`
+	Links  Links  `json:"links,omitempty"`
 }
 
 func NewSubject(con any) Subjects {
 	return parseSubjects(con)
 }
 
+// Label return the name of the subject, the label of its code in the
+// scheme when it has no name
+func (s *Subject) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	if scheme := LookupSubjectScheme(s.Scheme); scheme != nil {
+		if l, ok := scheme.Label(s.Code); ok {
+			return l
+		}
+	}
+	return s.Code
+}
+
 func (s Subjects) StringSlice() []string {
 	var subs []string
 	for _, sub := range s {
@@ -0,0 +1,175 @@
+package opds2
+
+// The built-in labels cover the main classes of the schemes and the genres
+// used most in catalogs, load the complete tables with SubjectScheme.Load.
+
+// bisacLabels are the BISAC sections, labelled by their general code, and
+// the main fiction genres
+var bisacLabels = map[string]string{
+	"ANT000000": "Antiques & Collectibles",
+	"ARC000000": "Architecture",
+	"ART000000": "Art",
+	"BIB000000": "Bibles",
+	"BIO000000": "Biography & Autobiography",
+	"BOD000000": "Body, Mind & Spirit",
+	"BUS000000": "Business & Economics",
+	"CGN000000": "Comics & Graphic Novels",
+	"CKB000000": "Cooking",
+	"COM000000": "Computers",
+	"CRA000000": "Crafts & Hobbies",
+	"DES000000": "Design",
+	"DRA000000": "Drama",
+	"EDU000000": "Education",
+	"FAM000000": "Family & Relationships",
+	"FIC000000": "Fiction",
+	"FOR000000": "Foreign Language Study",
+	"GAM000000": "Games & Activities",
+	"GAR000000": "Gardening",
+	"HEA000000": "Health & Fitness",
+	"HIS000000": "History",
+	"HOM000000": "House & Home",
+	"HUM000000": "Humor",
+	"JNF000000": "Juvenile Nonfiction",
+	"JUV000000": "Juvenile Fiction",
+	"LAN000000": "Language Arts & Disciplines",
+	"LAW000000": "Law",
+	"LCO000000": "Literary Collections",
+	"LIT000000": "Literary Criticism",
+	"MAT000000": "Mathematics",
+	"MED000000": "Medical",
+	"MUS000000": "Music",
+	"NAT000000": "Nature",
+	"NON000000": "Non-Classifiable",
+	"PER000000": "Performing Arts",
+	"PET000000": "Pets",
+	"PHI000000": "Philosophy",
+	"PHO000000": "Photography",
+	"POE000000": "Poetry",
+	"POL000000": "Political Science",
+	"PSY000000": "Psychology",
+	"REF000000": "Reference",
+	"REL000000": "Religion",
+	"SCI000000": "Science",
+	"SEL000000": "Self-Help",
+	"SOC000000": "Social Science",
+	"SPO000000": "Sports & Recreation",
+	"STU000000": "Study Aids",
+	"TEC000000": "Technology & Engineering",
+	"TRA000000": "Transportation",
+	"TRU000000": "True Crime",
+	"TRV000000": "Travel",
+	"YAF000000": "Young Adult Fiction",
+	"YAN000000": "Young Adult Nonfiction",
+
+	"FIC002000": "Fiction / Action & Adventure",
+	"FIC004000": "Fiction / Classics",
+	"FIC009000": "Fiction / Fantasy / General",
+	"FIC014000": "Fiction / Historical / General",
+	"FIC015000": "Fiction / Horror",
+	"FIC019000": "Fiction / Literary",
+	"FIC022000": "Fiction / Mystery & Detective / General",
+	"FIC027000": "Fiction / Romance / General",
+	"FIC028000": "Fiction / Science Fiction / General",
+	"FIC031000": "Fiction / Thrillers / General",
+}
+
+// themaLabels are the Thema subject categories, the qualifier groups and
+// the fiction genres
+var themaLabels = map[string]string{
+	"A": "The Arts",
+	"C": "Language and Linguistics",
+	"D": "Biography, Literature and Literary studies",
+	"F": "Fiction and Related items",
+	"G": "Reference, Information and Interdisciplinary subjects",
+	"J": "Society and Social Sciences",
+	"K": "Economics, Finance, Business and Management",
+	"L": "Law",
+	"M": "Medicine and Nursing",
+	"N": "History and Archaeology",
+	"P": "Mathematics and Science",
+	"Q": "Philosophy and Religion",
+	"R": "Earth Sciences, Geography, Environment, Planning",
+	"S": "Sports and Active outdoor recreation",
+	"T": "Technology, Engineering, Agriculture, Industrial processes",
+	"U": "Computing and Information Technology",
+	"V": "Health, Relationships and Personal development",
+	"W": "Lifestyle, Hobbies and Leisure",
+	"X": "Graphic novels, Comic books, Cartoons",
+	"Y": "Children's, Teenage and Educational",
+
+	"1": "Place qualifiers",
+	"2": "Language qualifiers",
+	"3": "Time period qualifiers",
+	"4": "Educational purpose qualifiers",
+	"5": "Interest qualifiers",
+	"6": "Style qualifiers",
+
+	"FB": "Fiction: general and literary",
+	"FF": "Crime and mystery fiction",
+	"FH": "Thriller / suspense fiction",
+	"FJ": "Adventure fiction",
+	"FK": "Horror and ghost stories",
+	"FL": "Science fiction",
+	"FM": "Fantasy",
+	"FR": "Romance",
+	"FV": "Historical fiction",
+}
+
+// bicLabels are the BIC subject categories and the fiction genres
+var bicLabels = map[string]string{
+	"A": "The arts",
+	"B": "Biography & true stories",
+	"C": "Language",
+	"D": "Literature & literary studies",
+	"E": "English language teaching",
+	"F": "Fiction & related items",
+	"G": "Reference, information & interdisciplinary subjects",
+	"H": "Humanities",
+	"J": "Society & social sciences",
+	"K": "Economics, finance, business & management",
+	"L": "Law",
+	"M": "Medicine",
+	"P": "Mathematics & science",
+	"R": "Earth sciences, geography, environment, planning",
+	"T": "Technology, engineering, agriculture",
+	"U": "Computing & information technology",
+	"V": "Health & personal development",
+	"W": "Lifestyle, sport & leisure",
+	"Y": "Children's, teenage & educational",
+
+	"FA": "Modern & contemporary fiction",
+	"FC": "Classic fiction",
+	"FF": "Crime & mystery",
+	"FH": "Thriller / suspense",
+	"FJ": "Adventure",
+	"FK": "Horror & ghost stories",
+	"FL": "Science fiction",
+	"FM": "Fantasy",
+	"FR": "Romance",
+	"FV": "Historical fiction",
+}
+
+// deweyLabels are the Dewey Decimal classes and the divisions of
+// literature
+var deweyLabels = map[string]string{
+	"000": "Computer science, information & general works",
+	"100": "Philosophy & psychology",
+	"200": "Religion",
+	"300": "Social sciences",
+	"400": "Language",
+	"500": "Science",
+	"600": "Technology",
+	"700": "Arts & recreation",
+	"800": "Literature",
+	"900": "History & geography",
+
+	"810": "American literature in English",
+	"820": "English & Old English literatures",
+	"830": "German & related literatures",
+	"840": "French & related literatures",
+	"850": "Italian, Romanian & related literatures",
+	"860": "Spanish, Portuguese, Galician literatures",
+	"870": "Latin & Italic literatures",
+	"880": "Classical & modern Greek literatures",
+	"890": "Other literatures",
+}
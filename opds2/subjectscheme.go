@@ -0,0 +1,215 @@
+package opds2
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// URIs of the subject schemes, used for Subject.Scheme
+const (
+	SchemeBISAC = "http://www.bisg.org/standards/bisac_subject/"
+	SchemeThema = "https://ns.editeur.org/thema/"
+	SchemeLCSH  = "http://id.loc.gov/authorities/subjects"
+	SchemeBIC   = "http://www.bic.org.uk/7/BIC-Standard-Subject-Categories/"
+	SchemeDDC   = "http://dewey.info/"
+)
+
+// SubjectScheme is a subject classification with the labels of its codes,
+// the built-in tables hold the main classes of each scheme and Load add
+// the complete lists published by the maintainers of the schemes
+type SubjectScheme struct {
+	Name string
+	URI  string
+	// Aliases are the other names of the scheme, like the MARC source
+	// codes, compared ignoring case
+	Aliases []string
+	// Headings is set for the schemes whose hierarchy is in the names, the
+	// subdivisions are separated by "--" like "France -- History"
+	Headings bool
+	parent   func(code string) string
+	labels   map[string]string
+}
+
+// subjectSchemesMu guard subjectSchemes, RegisterSubjectScheme may run
+// while the schemes are looked up
+var subjectSchemesMu sync.RWMutex
+
+var subjectSchemes = []*SubjectScheme{
+	{Name: "BISAC", URI: SchemeBISAC, Aliases: []string{"bisac", "bisacsh"}, parent: bisacParent, labels: bisacLabels},
+	{Name: "Thema", URI: SchemeThema, Aliases: []string{"thema"}, parent: prefixParent, labels: themaLabels},
+	{Name: "LCSH", URI: SchemeLCSH, Aliases: []string{"lcsh"}, Headings: true},
+	{Name: "BIC", URI: SchemeBIC, Aliases: []string{"bic", "bicssc"}, parent: prefixParent, labels: bicLabels},
+	{Name: "DDC", URI: SchemeDDC, Aliases: []string{"ddc", "dewey"}, parent: deweyParent, labels: deweyLabels},
+}
+
+// SubjectSchemes return the known subject schemes
+func SubjectSchemes() []*SubjectScheme {
+	subjectSchemesMu.RLock()
+	defer subjectSchemesMu.RUnlock()
+	return append([]*SubjectScheme(nil), subjectSchemes...)
+}
+
+// RegisterSubjectScheme add a subject scheme, replacing the scheme with
+// the same URI, it is safe to call while the schemes are in use
+func RegisterSubjectScheme(s *SubjectScheme) {
+	subjectSchemesMu.Lock()
+	defer subjectSchemesMu.Unlock()
+	for i, known := range subjectSchemes {
+		if known.URI == s.URI {
+			subjectSchemes[i] = s
+			return
+		}
+	}
+	subjectSchemes = append(subjectSchemes, s)
+}
+
+// LookupSubjectScheme return the scheme with the URI, name or alias given,
+// the URIs below the scheme URI like the Thema qualifiers match too, nil
+// for an unknown scheme
+func LookupSubjectScheme(scheme string) *SubjectScheme {
+	key := schemeKey(scheme)
+	if key == "" {
+		return nil
+	}
+	subjectSchemesMu.RLock()
+	defer subjectSchemesMu.RUnlock()
+	for _, s := range subjectSchemes {
+		if key == schemeKey(s.URI) || strings.EqualFold(scheme, s.Name) {
+			return s
+		}
+		for _, a := range s.Aliases {
+			if strings.EqualFold(scheme, a) {
+				return s
+			}
+		}
+	}
+	for _, s := range subjectSchemes {
+		if strings.HasPrefix(key, schemeKey(s.URI)+"/") {
+			return s
+		}
+	}
+	return nil
+}
+
+// NormalizeSubjectScheme return the URI of a known scheme given by name,
+// alias or URI, the URIs below it and other schemes are returned unchanged
+func NormalizeSubjectScheme(scheme string) string {
+	s := LookupSubjectScheme(scheme)
+	if s == nil || strings.HasPrefix(schemeKey(scheme), schemeKey(s.URI)+"/") {
+		return scheme
+	}
+	return s.URI
+}
+
+// schemeKey compare scheme URIs ignoring the protocol, www and the final
+// slash
+func schemeKey(uri string) string {
+	k := strings.ToLower(strings.TrimSpace(uri))
+	k = strings.TrimPrefix(strings.TrimPrefix(k, "https://"), "http://")
+	k = strings.TrimPrefix(k, "www.")
+	return strings.TrimSuffix(k, "/")
+}
+
+// Label return the label of code, false when the table of the scheme
+// doesn't have it
+func (s *SubjectScheme) Label(code string) (string, bool) {
+	l, ok := s.labels[subjectCode(code)]
+	return l, ok
+}
+
+// Parent return the code of the broader subject of code, empty for a main
+// class or a scheme without hierarchy in its codes
+func (s *SubjectScheme) Parent(code string) string {
+	if s.parent == nil {
+		return ""
+	}
+	return s.parent(subjectCode(code))
+}
+
+// Ancestors return the codes of the broader subjects of code, the main
+// class first
+func (s *SubjectScheme) Ancestors(code string) []string {
+	var codes []string
+	for p := s.Parent(code); p != "" && p != code; p = s.Parent(p) {
+		codes = append([]string{p}, codes...)
+		code = p
+	}
+	return codes
+}
+
+// Add set the label of code, load the tables before looking them up
+// concurrently
+func (s *SubjectScheme) Add(code string, label string) {
+	if s.labels == nil {
+		s.labels = make(map[string]string)
+	}
+	s.labels[subjectCode(code)] = strings.TrimSpace(label)
+}
+
+// Load add the labels of a CSV table of code and label columns, lines
+// with less than two columns are skipped
+func (s *SubjectScheme) Load(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(rec) >= 2 && strings.TrimSpace(rec[0]) != "" {
+			s.Add(rec[0], rec[1])
+		}
+	}
+}
+
+// subjectCode normalize a code for the lookup tables, codes are compared
+// ignoring case
+func subjectCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// bisacParent return the general code of the section, FIC000000 for
+// FIC027000
+func bisacParent(code string) string {
+	if len(code) != 9 || code[3:] == "000000" {
+		return ""
+	}
+	return code[:3] + "000000"
+}
+
+// prefixParent return the code without its last character, the Thema and
+// BIC codes get narrower with each character, Thema national extensions
+// like 1DDU-GB-S drop their last part
+func prefixParent(code string) string {
+	if i := strings.LastIndex(code, "-"); i > 0 {
+		return code[:i]
+	}
+	if len(code) <= 1 {
+		return ""
+	}
+	return code[:len(code)-1]
+}
+
+// deweyParent return the broader Dewey class, 823.91 for 823.914, 823 for
+// 823.9, 820 for 823 and 800 for 820
+func deweyParent(code string) string {
+	if strings.Contains(code, ".") {
+		return strings.TrimSuffix(code[:len(code)-1], ".")
+	}
+	if len(code) != 3 || strings.Trim(code, "0123456789") != "" {
+		return ""
+	}
+	switch {
+	case code[2] != '0':
+		return code[:2] + "0"
+	case code[1] != '0':
+		return code[:1] + "00"
+	}
+	return ""
+}
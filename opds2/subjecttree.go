@@ -0,0 +1,238 @@
+package opds2
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+)
+
+// SubjectNode is a subject of a SubjectTree, Code is the code in the scheme
+// or the heading for the subjects without code
+type SubjectNode struct {
+	Scheme string
+	Code   string
+	Label  string
+	// Count is the number of publications of the subject and of its
+	// narrower subjects
+	Count int
+	// Publications are the publications having the subject itself
+	Publications []*Publication
+	Children     []*SubjectNode
+	parent       *SubjectNode
+}
+
+// Parent return the broader subject, the scheme for a main class and nil
+// for the root
+func (n *SubjectNode) Parent() *SubjectNode {
+	return n.parent
+}
+
+// SubjectTree is the hierarchy of the subjects of a catalog, the root hold
+// a node per known scheme, whose children are the main classes, and the
+// subjects without known scheme
+type SubjectTree struct {
+	Root  *SubjectNode
+	nodes map[[2]string]*SubjectNode
+}
+
+// SubjectTree build the subject tree of the publications of the feed and
+// its groups
+func (feed *Feed) SubjectTree() *SubjectTree {
+	return NewSubjectTree(feed.Query().All())
+}
+
+// NewSubjectTree build the subject tree of pubs, the broader subjects of
+// codes having a label in their scheme are added, LCSH like headings are
+// split on their "--" subdivisions
+func NewSubjectTree(pubs []Publication) *SubjectTree {
+	t := &SubjectTree{
+		Root:  &SubjectNode{Label: "Subjects"},
+		nodes: make(map[[2]string]*SubjectNode),
+	}
+	for i := range pubs {
+		p := &pubs[i]
+		counted := make(map[*SubjectNode]bool)
+		for _, s := range p.Metadata.Subject {
+			n := t.add(s)
+			if n == nil {
+				continue
+			}
+			n.Publications = appendPublication(n.Publications, p)
+			for ; n != nil; n = n.parent {
+				if !counted[n] {
+					counted[n] = true
+					n.Count++
+				}
+			}
+		}
+	}
+	t.Root.sortChildren()
+	return t
+}
+
+// Find return the node of the code, or the heading, in scheme, the scheme
+// node for an empty code, the subjects without known scheme are found by
+// their lowercased name, nil when the tree doesn't have it
+func (t *SubjectTree) Find(scheme string, code string) *SubjectNode {
+	if s := LookupSubjectScheme(scheme); s != nil {
+		scheme = s.URI
+		if !s.Headings {
+			code = subjectCode(code)
+		}
+	} else {
+		code = strings.ToLower(strings.TrimSpace(code))
+	}
+	return t.nodes[[2]string{scheme, code}]
+}
+
+// add add the node of the subject and of its broader subjects
+func (t *SubjectTree) add(s *Subject) *SubjectNode {
+	scheme := LookupSubjectScheme(s.Scheme)
+	if scheme == nil {
+		name := strings.TrimSpace(s.Label())
+		if name == "" {
+			return nil
+		}
+		return t.node(t.Root, s.Scheme, strings.ToLower(name), name)
+	}
+	parent := t.node(t.Root, scheme.URI, "", scheme.Name)
+
+	if scheme.Headings || s.Code == "" {
+		var path []string
+		for _, h := range strings.Split(s.Label(), "--") {
+			if h = strings.TrimSpace(h); h != "" {
+				path = append(path, h)
+			}
+		}
+		if len(path) == 0 {
+			return nil
+		}
+		for i := range path {
+			parent = t.node(parent, scheme.URI, strings.Join(path[:i+1], " -- "), path[i])
+		}
+		return parent
+	}
+
+	// the broader subjects missing from the table of the scheme are left
+	// out, 823.914 is under 820 until the complete Dewey table is loaded
+	code := subjectCode(s.Code)
+	for _, c := range scheme.Ancestors(code) {
+		if label, ok := scheme.Label(c); ok {
+			parent = t.node(parent, scheme.URI, c, label)
+		}
+	}
+	label := s.Name
+	if l, ok := scheme.Label(code); ok && label == "" {
+		label = l
+	}
+	if label == "" {
+		label = code
+	}
+	return t.node(parent, scheme.URI, code, label)
+}
+
+// node return the node of code in scheme, created as a child of parent
+func (t *SubjectTree) node(parent *SubjectNode, scheme string, code string, label string) *SubjectNode {
+	key := [2]string{scheme, code}
+	if n, ok := t.nodes[key]; ok {
+		return n
+	}
+	n := &SubjectNode{Scheme: scheme, Code: code, Label: label, parent: parent}
+	t.nodes[key] = n
+	parent.Children = append(parent.Children, n)
+	return n
+}
+
+// sortChildren order the subjects by code, in the order of their scheme,
+// and the headings and the subjects without scheme by label
+func (n *SubjectNode) sortChildren() {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if s := LookupSubjectScheme(a.Scheme); s != nil && !s.Headings && a.Code != "" && b.Code != "" {
+			return a.Code < b.Code
+		}
+		return strings.ToLower(a.Label) < strings.ToLower(b.Label)
+	})
+	for _, c := range n.Children {
+		c.sortChildren()
+	}
+}
+
+func appendPublication(pubs []*Publication, p *Publication) []*Publication {
+	for _, existing := range pubs {
+		if existing == p {
+			return pubs
+		}
+	}
+	return append(pubs, p)
+}
+
+// Feed return the navigation feed of a node, linking to its narrower
+// subjects with their number of publications, with the publications having
+// the subject itself, href give the url of the feed of a node
+func (t *SubjectTree) Feed(n *SubjectNode, href func(*SubjectNode) string) Feed {
+	if n == nil {
+		n = t.Root
+	}
+	feed := Feed{Links: Links{}}
+	feed.Metadata.Title.SingleString = n.Label
+	feed.AddLink(href(n), "self", mediatype.OPDS2, false)
+	if n.parent != nil {
+		feed.AddLink(href(n.parent), "up", mediatype.OPDS2, false)
+	}
+	for _, c := range n.Children {
+		feed.Navigation = append(feed.Navigation, &Link{
+			Href:       href(c),
+			TypeLink:   mediatype.OPDS2,
+			Rel:        []string{"subsection"},
+			Title:      c.Label,
+			Properties: &Properties{NumberOfItems: c.Count},
+		})
+	}
+	for _, p := range n.Publications {
+		feed.Publications = append(feed.Publications, *p)
+	}
+	if len(feed.Publications) > 0 {
+		feed.Metadata.NumberOfItems = len(feed.Publications)
+	}
+	return feed
+}
+
+// Feeds return the feed of every node of the tree indexed by their href
+func (t *SubjectTree) Feeds(href func(*SubjectNode) string) map[string]Feed {
+	feeds := make(map[string]Feed)
+	var walk func(n *SubjectNode)
+	walk = func(n *SubjectNode) {
+		feeds[href(n)] = t.Feed(n, href)
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(t.Root)
+	return feeds
+}
+
+// SubjectHref return an href function for the feeds of a subject tree
+// giving the scheme and code of the nodes as the scheme and subject query
+// parameters of base, base itself for the root
+func SubjectHref(base string) func(*SubjectNode) string {
+	return func(n *SubjectNode) string {
+		if n.parent == nil {
+			return base
+		}
+		q := url.Values{}
+		if n.Scheme != "" {
+			q.Set("scheme", n.Scheme)
+		}
+		if n.Code != "" {
+			q.Set("subject", n.Code)
+		}
+		sep := "?"
+		if strings.Contains(base, "?") {
+			sep = "&"
+		}
+		return base + sep + q.Encode()
+	}
+}
@@ -0,0 +1,239 @@
+package opds2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// The model types are decoded by the hand written parsers used for feeds,
+// so a publication or a link decoded on its own with encoding/json accept
+// the same shapes: string or object titles and names, string or array
+// relations and languages, alternative key spellings.
+
+// decodeJSON unmarshal data in generic values for the parsers, numbers are
+// kept as json.Number so prices stay exact, null give a nil value
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// decodeObject unmarshal a JSON object, null give a nil map
+func decodeObject(data []byte, what string) (map[string]any, error) {
+	v, err := decodeJSON(data)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("opds2: " + what + " is not an object")
+	}
+	return m, nil
+}
+
+// UnmarshalJSON read a string or an object of strings by language
+func (m *MultiLanguage) UnmarshalJSON(data []byte) error {
+	v, err := decodeJSON(data)
+	if err != nil || v == nil {
+		return err
+	}
+	switch v.(type) {
+	case string, map[string]any:
+		*m = parseMultiLanguage(v)
+		return nil
+	}
+	return errors.New("opds2: multilingual string is neither a string nor an object")
+}
+
+// UnmarshalJSON read a string or an array of strings
+func (r *StringOrArray) UnmarshalJSON(data []byte) error {
+	v, err := decodeJSON(data)
+	if err != nil || v == nil {
+		return err
+	}
+	switch v.(type) {
+	case string, []any:
+		*r = parseStringOrArray(v)
+		return nil
+	}
+	return errors.New("opds2: expected a string or an array of strings")
+}
+
+// UnmarshalJSON read the metadata of a feed, a facet or a group
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	info, err := decodeObject(data, "metadata")
+	if err != nil || info == nil {
+		return err
+	}
+	*m = parseMetadata(info)
+	return nil
+}
+
+// UnmarshalJSON read a link
+func (l *Link) UnmarshalJSON(data []byte) error {
+	info, err := decodeObject(data, "link")
+	if err != nil || info == nil {
+		return err
+	}
+	*l = *parseLink(info)
+	return nil
+}
+
+// UnmarshalJSON read the properties of a link
+func (p *Properties) UnmarshalJSON(data []byte) error {
+	info, err := decodeObject(data, "properties")
+	if err != nil || info == nil {
+		return err
+	}
+	*p = *parseProperties(info)
+	return nil
+}
+
+// UnmarshalJSON read a facet
+func (f *Facet) UnmarshalJSON(data []byte) error {
+	info, err := decodeObject(data, "facet")
+	if err != nil || info == nil {
+		return err
+	}
+	*f = parseFacet(info)
+	return nil
+}
+
+// UnmarshalJSON read a group
+func (g *Group) UnmarshalJSON(data []byte) error {
+	info, err := decodeObject(data, "group")
+	if err != nil || info == nil {
+		return err
+	}
+	*g = parseGroup(info)
+	return nil
+}
+
+// UnmarshalJSON read a publication
+func (publication *Publication) UnmarshalJSON(data []byte) error {
+	info, err := decodeObject(data, "publication")
+	if err != nil || info == nil {
+		return err
+	}
+	*publication = Publication{}
+	parsePublication(info, publication)
+	return nil
+}
+
+// UnmarshalJSON read the metadata of a publication
+func (m *PublicationMetadata) UnmarshalJSON(data []byte) error {
+	info, err := decodeObject(data, "metadata")
+	if err != nil || info == nil {
+		return err
+	}
+	*m = PublicationMetadata{}
+	parsePublicationMetadata(info, m)
+	return nil
+}
+
+// UnmarshalJSON read a contributor given by its name or as an object
+func (c *Contributor) UnmarshalJSON(data []byte) error {
+	v, err := decodeJSON(data)
+	if err != nil || v == nil {
+		return err
+	}
+	if !isContributor(v) {
+		return errors.New("opds2: contributor is neither a string nor an object")
+	}
+	*c = *parseContributor(v)
+	return nil
+}
+
+// UnmarshalJSON read a contributor or an array of contributors
+func (c *Contributors) UnmarshalJSON(data []byte) error {
+	v, err := decodeJSON(data)
+	if err != nil || v == nil {
+		return err
+	}
+	list, ok := v.([]any)
+	if !ok {
+		list = []any{v}
+	}
+	for _, con := range list {
+		if !isContributor(con) {
+			return errors.New("opds2: contributor is neither a string nor an object")
+		}
+	}
+	*c = parseContributors(v)
+	return nil
+}
+
+// isContributor check that v is a contributor given by its name or as an
+// object
+func isContributor(v any) bool {
+	switch v.(type) {
+	case string, map[string]any:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON read a collection given by its name or as an object
+func (c *Collection) UnmarshalJSON(data []byte) error {
+	v, err := decodeJSON(data)
+	if err != nil || v == nil {
+		return err
+	}
+	*c = *parseCollection(v)
+	return nil
+}
+
+// UnmarshalJSON read a collection or an array of collections
+func (c *Collections) UnmarshalJSON(data []byte) error {
+	v, err := decodeJSON(data)
+	if err != nil || v == nil {
+		return err
+	}
+	*c = parseCollections(v)
+	return nil
+}
+
+// UnmarshalJSON read the series and collections of a publication
+func (b *BelongsTo) UnmarshalJSON(data []byte) error {
+	info, err := decodeObject(data, "belongsTo")
+	if err != nil || info == nil {
+		return err
+	}
+	*b = *parseBelongsTo(info)
+	return nil
+}
+
+// UnmarshalJSON read a subject given by its name or as an object
+func (s *Subject) UnmarshalJSON(data []byte) error {
+	v, err := decodeJSON(data)
+	if err != nil || v == nil {
+		return err
+	}
+	*s = *parseSubject(v)
+	return nil
+}
+
+// UnmarshalJSON read a subject or an array of subjects
+func (s *Subjects) UnmarshalJSON(data []byte) error {
+	v, err := decodeJSON(data)
+	if err != nil || v == nil {
+		return err
+	}
+	*s = parseSubjects(v)
+	return nil
+}
+
+// UnmarshalJSON read an indirect acquisition and its children
+func (i *IndirectAcquisition) UnmarshalJSON(data []byte) error {
+	info, err := decodeObject(data, "indirectAcquisition")
+	if err != nil || info == nil {
+		return err
+	}
+	*i = parseIndirectAcquisition(info)
+	return nil
+}
@@ -0,0 +1,230 @@
+package opds2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// corpusKeyAliases are the alternative keys of the corpus written back under
+// their canonical name
+var corpusKeyAliases = map[string]string{
+	"sortAs":     "sort_as",
+	"belongs_to": "belongsTo",
+}
+
+func TestUnmarshalCorpus(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no document in testdata")
+	}
+	for _, path := range paths {
+		path := path
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			orig := decodeGenericJSON(t, data)
+
+			decode := func(b []byte) any {
+				var v any = &Publication{}
+				if corpusIsFeed(orig) {
+					v = &Feed{}
+				}
+				if err := json.Unmarshal(b, v); err != nil {
+					t.Fatal(err)
+				}
+				return v
+			}
+
+			out, err := json.Marshal(decode(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := json.Marshal(decode(out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, again) {
+				t.Errorf("second round trip changed the document:\n%s\n%s", out, again)
+			}
+			corpusLost("", orig, decodeGenericJSON(t, out), func(p string) {
+				t.Errorf("round trip changed %s", p)
+			})
+		})
+	}
+}
+
+func TestUnmarshalInvalidTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		v    any
+	}{
+		{"multilingual number", `42`, &MultiLanguage{}},
+		{"multilingual array", `["a"]`, &MultiLanguage{}},
+		{"multilingual bool", `true`, &MultiLanguage{}},
+		{"string or array number", `42`, &StringOrArray{}},
+		{"string or array object", `{"a":"b"}`, &StringOrArray{}},
+		{"contributor number", `42`, &Contributor{}},
+		{"contributor array", `["a"]`, &Contributor{}},
+		{"contributors number", `42`, &Contributors{}},
+		{"contributors number element", `["a", 42]`, &Contributors{}},
+	}
+	for _, tt := range tests {
+		if err := json.Unmarshal([]byte(tt.data), tt.v); err == nil {
+			t.Errorf("%s: no error for %s", tt.name, tt.data)
+		}
+	}
+}
+
+func TestUnmarshalValidTypes(t *testing.T) {
+	var title MultiLanguage
+	if err := json.Unmarshal([]byte(`{"en":"Moby Dick","fr":"Moby Dick ou le cachalot"}`), &title); err != nil {
+		t.Fatal(err)
+	}
+	if title.MultiString["fr"] != "Moby Dick ou le cachalot" {
+		t.Errorf("title = %v", title.MultiString)
+	}
+
+	var rels StringOrArray
+	if err := json.Unmarshal([]byte(`"self"`), &rels); err != nil || len(rels) != 1 || rels[0] != "self" {
+		t.Errorf("rel = %v, %v", rels, err)
+	}
+
+	var null MultiLanguage
+	if err := json.Unmarshal([]byte(`null`), &null); err != nil {
+		t.Errorf("null: %v", err)
+	}
+
+	var cons Contributors
+	if err := json.Unmarshal([]byte(`["Herman Melville", {"name": "Jean Giono", "role": "trl"}]`), &cons); err != nil {
+		t.Fatal(err)
+	}
+	if len(cons) != 2 || cons[0].Name.String() != "Herman Melville" || !cons[1].HasRole("trl") {
+		t.Errorf("contributors = %v", cons)
+	}
+
+	var con Contributor
+	if err := json.Unmarshal([]byte(`"Herman Melville"`), &con); err != nil || con.Name.String() != "Herman Melville" {
+		t.Errorf("contributor = %v, %v", con.Name, err)
+	}
+}
+
+func decodeGenericJSON(t *testing.T, data []byte) any {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func corpusIsFeed(doc any) bool {
+	m, _ := doc.(map[string]any)
+	for _, k := range []string{"publications", "navigation", "groups", "facets"} {
+		if _, ok := m[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// corpusLost report the keys, array elements and values of a missing or
+// changed in b, the only shape changes allowed are a single value encoded
+// in an array and a name given as a string encoded as an object
+func corpusLost(path string, a, b any, report func(string)) {
+	if bl, ok := b.([]any); ok && len(bl) == 1 {
+		if _, isList := a.([]any); !isList {
+			corpusLost(path, a, bl[0], report)
+			return
+		}
+	}
+	switch av := a.(type) {
+	case map[string]any:
+		bm, ok := b.(map[string]any)
+		if !ok {
+			report(fmt.Sprintf("%s: object became %v", path, b))
+			return
+		}
+		keys := make([]string, 0, len(av))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			bk := k
+			if alias, ok := corpusKeyAliases[k]; ok {
+				bk = alias
+			}
+			bv, ok := bm[bk]
+			if !ok {
+				if !corpusIsEmpty(av[k]) {
+					report(path + "/" + k + ": missing")
+				}
+				continue
+			}
+			corpusLost(path+"/"+k, av[k], bv, report)
+		}
+	case []any:
+		bl, ok := b.([]any)
+		if !ok {
+			if len(av) == 1 {
+				corpusLost(path, av[0], b, report)
+				return
+			}
+			report(fmt.Sprintf("%s: array became %v", path, b))
+			return
+		}
+		if len(bl) != len(av) {
+			report(path + ": " + strconv.Itoa(len(av)) + " elements became " + strconv.Itoa(len(bl)))
+		}
+		for i, v := range av {
+			if i >= len(bl) {
+				break
+			}
+			corpusLost(path+"/"+strconv.Itoa(i), v, bl[i], report)
+		}
+	case string:
+		if bm, ok := b.(map[string]any); ok {
+			corpusLost(path+"/name", a, bm["name"], report)
+			return
+		}
+		if bv, ok := b.(string); !ok || bv != av {
+			report(fmt.Sprintf("%s: %q became %v", path, av, b))
+		}
+	case json.Number:
+		bv, ok := b.(json.Number)
+		x, okA := new(big.Rat).SetString(av.String())
+		y, okB := new(big.Rat).SetString(bv.String())
+		if !ok || !okA || !okB || x.Cmp(y) != 0 {
+			report(fmt.Sprintf("%s: %s became %v", path, av, b))
+		}
+	default:
+		if a != b {
+			report(fmt.Sprintf("%s: %v became %v", path, a, b))
+		}
+	}
+}
+
+func corpusIsEmpty(v any) bool {
+	switch vv := v.(type) {
+	case []any:
+		return len(vv) == 0
+	case map[string]any:
+		return len(vv) == 0
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package opdsserver
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ohzqq/libopds2-go/opds2"
+)
+
+// decorate return a copy of the feed with the self, start, up, search and
+// pagination links added when the catalog didn't provide them
+func (s *Server) decorate(r *http.Request, feed *opds2.Feed, up string) *opds2.Feed {
+	f := *feed
+	f.Links = append(opds2.Links(nil), feed.Links...)
+
+	addLink := func(href string, rel string, typeLink string, templated bool) {
+		if hasRel(f.Links, rel) {
+			return
+		}
+		f.AddLink(href, rel, typeLink, templated)
+	}
+
+	addLink(selfURL(r), "self", TypeOPDS2, false)
+	addLink(s.URL(""), "start", TypeOPDS2, false)
+	if up != "" {
+		addLink(up, "up", TypeOPDS2, false)
+	}
+	addLink(s.SearchURL(), "search", TypeOPDS2, true)
+
+	m := f.Metadata
+	if m.ItemsPerPage > 0 && m.NumberOfItems > m.ItemsPerPage {
+		current := m.CurrentPage
+		if current < 1 {
+			current = 1
+		}
+		last := (m.NumberOfItems + m.ItemsPerPage - 1) / m.ItemsPerPage
+		addLink(pageURL(r, 1), "first", TypeOPDS2, false)
+		if current > 1 {
+			addLink(pageURL(r, current-1), "previous", TypeOPDS2, false)
+		}
+		if current < last {
+			addLink(pageURL(r, current+1), "next", TypeOPDS2, false)
+		}
+		addLink(pageURL(r, last), "last", TypeOPDS2, false)
+	}
+
+	f.Publications = s.withSelfLinks(feed.Publications)
+	f.Groups = append([]opds2.Group(nil), feed.Groups...)
+	for i := range f.Groups {
+		f.Groups[i].Publications = s.withSelfLinks(f.Groups[i].Publications)
+	}
+
+	return &f
+}
+
+// withSelfLinks return a copy of the publications with a self link to
+// their publication document when they have an identifier
+func (s *Server) withSelfLinks(pubs []opds2.Publication) []opds2.Publication {
+	if pubs == nil {
+		return nil
+	}
+	res := make([]opds2.Publication, len(pubs))
+	for i, p := range pubs {
+		if p.Metadata.Identifier != "" && !hasRel(p.Links, "self") {
+			self := &opds2.Link{Href: s.PublicationURL(p.Metadata.Identifier), Rel: []string{"self"}, TypeLink: TypeOPDS2Publication}
+			p.Links = append(opds2.Links{self}, p.Links...)
+		}
+		res[i] = p
+	}
+	return res
+}
+
+func hasRel(links opds2.Links, rel string) bool {
+	for _, l := range links {
+		for _, r := range l.Rel {
+			if r == rel {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func withoutRel(links opds2.Links, rel string) opds2.Links {
+	var filtered opds2.Links
+	for _, l := range links {
+		keep := true
+		for _, r := range l.Rel {
+			if r == rel {
+				keep = false
+			}
+		}
+		if keep {
+			filtered = append(filtered, l)
+		}
+	}
+	return filtered
+}
+
+// selfURL return the path and query of the request
+func selfURL(r *http.Request) string {
+	return r.URL.RequestURI()
+}
+
+// pageURL return the request url with the page parameter set to page
+func pageURL(r *http.Request, page int) string {
+	u := url.URL{Path: r.URL.Path}
+	q := r.URL.Query()
+	if page > 1 {
+		q.Set("page", strconv.Itoa(page))
+	} else {
+		q.Del("page")
+	}
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
@@ -0,0 +1,96 @@
+package opdsserver
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ohzqq/libopds2-go/mediatype"
+)
+
+// Media types served
+const (
+	TypeOPDS2            = mediatype.OPDS2
+	TypeOPDS2Publication = mediatype.OPDS2Publication
+	TypeAtom             = mediatype.Atom
+	TypeOpenSearch       = mediatype.OpenSearch
+	TypeHTML             = mediatype.HTML + "; charset=utf-8"
+)
+
+// acceptRange is a media range of an Accept header with its quality
+type acceptRange struct {
+	Type    string
+	Subtype string
+	Q       float64
+}
+
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mt := strings.ToLower(strings.TrimSpace(params[0]))
+		if mt == "" {
+			continue
+		}
+		t, st, ok := strings.Cut(mt, "/")
+		if !ok {
+			continue
+		}
+		ranges = append(ranges, acceptRange{Type: t, Subtype: st, Q: qValue(params[1:])})
+	}
+	return ranges
+}
+
+// qValue return the q parameter of a header element, 1 when absent
+func qValue(params []string) float64 {
+	for _, p := range params {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if strings.EqualFold(strings.TrimSpace(k), "q") {
+			if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return q
+			}
+		}
+	}
+	return 1
+}
+
+// quality return the quality given by the Accept ranges to the media type
+// offer, the most specific matching range wins
+func quality(ranges []acceptRange, offer string) float64 {
+	offer, _, _ = strings.Cut(offer, ";")
+	t, st, _ := strings.Cut(offer, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.Type == t && r.Subtype == st:
+			s = 2
+		case r.Type == t && r.Subtype == "*":
+			s = 1
+		case r.Type == "*" && r.Subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.Q, s
+		}
+	}
+	return q
+}
+
+// negotiate return the offer preferred by the Accept header, ties and an
+// empty header favour the first offer. Each offer lists the media types it
+// can be requested with.
+func negotiate(header string, offers ...[]string) int {
+	if strings.TrimSpace(header) == "" {
+		return 0
+	}
+	ranges := parseAccept(header)
+	best, bestQ := 0, 0.0
+	for i, types := range offers {
+		for _, t := range types {
+			if q := quality(ranges, t); q > bestQ {
+				best, bestQ = i, q
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,248 @@
+package opdsserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/ohzqq/libopds2-go/html"
+	"github.com/ohzqq/libopds2-go/mediatype"
+	"github.com/ohzqq/libopds2-go/opds2"
+)
+
+// gzipMinSize is the size under which responses are not compressed
+const gzipMinSize = 1024
+
+// offers served for feeds and publications, in order of preference
+var (
+	offerOPDS2 = []string{TypeOPDS2, TypeOPDS2Publication, mediatype.JSON}
+	offerAtom  = []string{TypeAtom, "application/xml", "text/xml"}
+	offerHTML  = []string{TypeHTML, mediatype.XHTML}
+)
+
+// format served to the client
+const (
+	formatOPDS2 = iota
+	formatAtom
+	formatHTML
+)
+
+// negotiateFormat pick the format of the response, html is only offered
+// when the server has a renderer
+func (s *Server) negotiateFormat(r *http.Request) int {
+	if s.HTML == nil {
+		return negotiate(r.Header.Get("Accept"), offerOPDS2, offerAtom)
+	}
+	return negotiate(r.Header.Get("Accept"), offerOPDS2, offerAtom, offerHTML)
+}
+
+func (s *Server) serveFeed(w http.ResponseWriter, r *http.Request, feed *opds2.Feed, err error, up string) {
+	if err != nil {
+		s.serveError(w, err)
+		return
+	}
+	if feed == nil {
+		s.serveError(w, ErrNotFound)
+		return
+	}
+	f := s.decorate(r, feed, up)
+
+	var modified time.Time
+	if f.Metadata.Modified != nil {
+		modified = *f.Metadata.Modified
+	}
+
+	tags := opds2.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	switch s.negotiateFormat(r) {
+	case formatHTML:
+		var b bytes.Buffer
+		if err := s.renderer(tags).RenderFeed(&b, f.Localize(tags...)); err != nil {
+			s.serveError(w, err)
+			return
+		}
+		writeResponse(w, r, b.Bytes(), TypeHTML, modified)
+	case formatAtom:
+		atom := f.Localize(tags...).ToOPDS1(selfURL(r))
+		for i, l := range atom.Links {
+			if l.Rel == "search" {
+				atom.Links[i].Href = s.URL("opensearch.xml")
+				atom.Links[i].TypeLink = TypeOpenSearch
+			}
+		}
+		var b bytes.Buffer
+		if err := atom.Write(&b); err != nil {
+			s.serveError(w, err)
+			return
+		}
+		writeResponse(w, r, b.Bytes(), TypeAtom+";profile=opds-catalog;kind="+feedKind(f), modified)
+	default:
+		b, err := json.Marshal(f)
+		if err != nil {
+			s.serveError(w, err)
+			return
+		}
+		writeResponse(w, r, b, TypeOPDS2, modified)
+	}
+}
+
+func (s *Server) servePublication(w http.ResponseWriter, r *http.Request, pub *opds2.Publication, err error) {
+	if err != nil {
+		s.serveError(w, err)
+		return
+	}
+	if pub == nil {
+		s.serveError(w, ErrNotFound)
+		return
+	}
+	p := *pub
+	p.Links = append(opds2.Links{{Href: selfURL(r), Rel: []string{"self"}, TypeLink: TypeOPDS2Publication}}, withoutRel(p.Links, "self")...)
+
+	var modified time.Time
+	if p.Metadata.Modified != nil {
+		modified = *p.Metadata.Modified
+	}
+
+	tags := opds2.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	switch s.negotiateFormat(r) {
+	case formatHTML:
+		var b bytes.Buffer
+		if err := s.renderer(tags).RenderPublication(&b, p.Localize(tags...)); err != nil {
+			s.serveError(w, err)
+			return
+		}
+		writeResponse(w, r, b.Bytes(), TypeHTML, modified)
+	case formatAtom:
+		entry := p.Localize(tags...).ToOPDS1()
+		entry.Links[0].TypeLink = TypeAtom + ";type=entry;profile=opds-catalog"
+		var b bytes.Buffer
+		if err := entry.Write(&b); err != nil {
+			s.serveError(w, err)
+			return
+		}
+		writeResponse(w, r, b.Bytes(), TypeAtom+";type=entry;profile=opds-catalog", modified)
+	default:
+		b, err := json.Marshal(p)
+		if err != nil {
+			s.serveError(w, err)
+			return
+		}
+		writeResponse(w, r, b, TypeOPDS2Publication, modified)
+	}
+}
+
+// renderer return the html renderer of the server for the languages of
+// the request, the first one is the lang of the page
+func (s *Server) renderer(tags []language.Tag) *html.Renderer {
+	if len(tags) == 0 || tags[0] == language.Und {
+		return s.HTML
+	}
+	r := *s.HTML
+	r.Lang = tags[0].String()
+	return &r
+}
+
+func (s *Server) serveOpenSearch(w http.ResponseWriter, r *http.Request) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<OpenSearchDescription xmlns="http://a9.com/-/spec/opensearch/1.1/">
+  <ShortName>%s</ShortName>
+  <Description>%s</Description>
+  <Url type="%s;profile=opds-catalog;kind=acquisition" template="%s?query={searchTerms}"/>
+</OpenSearchDescription>
+`, xmlEscape(s.Title), xmlEscape(s.Title), TypeAtom, xmlEscape(s.URL("search")))
+	writeResponse(w, r, b.Bytes(), TypeOpenSearch, time.Time{})
+}
+
+// writeResponse write the body with its validators, answer 304 to matching
+// conditional requests and compress the body when the client accept gzip
+func writeResponse(w http.ResponseWriter, r *http.Request, body []byte, contentType string, modified time.Time) {
+	sum := sha1.Sum(body)
+	etag := `W/"` + hex.EncodeToString(sum[:]) + `"`
+
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	h.Set("Vary", "Accept, Accept-Encoding, Accept-Language")
+	h.Set("ETag", etag)
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, modified) {
+		h.Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if len(body) >= gzipMinSize && acceptsGzip(r) {
+		h.Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodHead {
+			return
+		}
+		gz := gzip.NewWriter(w)
+		gz.Write(body)
+		gz.Close()
+		return
+	}
+
+	h.Set("Content-Length", fmt.Sprint(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimSpace(t)
+			if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(part, ";")
+		if strings.EqualFold(strings.TrimSpace(params[0]), "gzip") {
+			return qValue(params[1:]) > 0
+		}
+	}
+	return false
+}
+
+// feedKind return the kind of the OPDS 1.x catalog matching the feed
+func feedKind(f *opds2.Feed) string {
+	if len(f.Publications) > 0 {
+		return "acquisition"
+	}
+	for _, g := range f.Groups {
+		if len(g.Publications) > 0 {
+			return "acquisition"
+		}
+	}
+	return "navigation"
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,151 @@
+// Package opdsserver serve a catalog as OPDS 2.0 or OPDS 1.x depending on
+// the Accept header of the request
+package opdsserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ohzqq/libopds2-go/html"
+	"github.com/ohzqq/libopds2-go/opds2"
+)
+
+// ErrNotFound is returned by a catalog when a feed or publication doesn't
+// exist, the server answer with a 404
+var ErrNotFound = errors.New("opdsserver: not found")
+
+// Catalog is the backend providing the feeds served
+type Catalog interface {
+	// Root return the start feed of the catalog
+	Root(ctx context.Context) (*opds2.Feed, error)
+	// Browse return the page of the feed at path, path never starts with a /
+	Browse(ctx context.Context, path string, page int) (*opds2.Feed, error)
+	// Search return the page of the results for query
+	Search(ctx context.Context, query string, page int) (*opds2.Feed, error)
+	// Publication return the publication with the identifier id
+	Publication(ctx context.Context, id string) (*opds2.Publication, error)
+}
+
+// Server is an http.Handler serving a Catalog, the routes are relative to
+// Prefix:
+//
+//	/                 root feed
+//	/browse/{path}    feeds returned by Catalog.Browse
+//	/search?query=    search results
+//	/publications/{id} publication documents
+//	/opensearch.xml   OpenSearch description used by OPDS 1.x clients
+type Server struct {
+	Catalog Catalog
+	Prefix  string
+	// Title of the catalog used in the OpenSearch description
+	Title string
+	// HTML render the feeds for browsers asking for text/html, the
+	// catalog is only served as OPDS when nil
+	HTML *html.Renderer
+}
+
+// New create a server for catalog mounted at prefix
+func New(catalog Catalog, prefix string) *Server {
+	return &Server{
+		Catalog: catalog,
+		Prefix:  strings.TrimSuffix(prefix, "/"),
+		Title:   "OPDS Catalog",
+		HTML:    html.New(),
+	}
+}
+
+// ServeHTTP route the request to the catalog
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, s.Prefix)
+	p = strings.TrimPrefix(p, "/")
+	page := pageParam(r)
+	ctx := r.Context()
+
+	switch {
+	case p == "":
+		feed, err := s.Catalog.Root(ctx)
+		s.serveFeed(w, r, feed, err, "")
+	case p == "opensearch.xml":
+		s.serveOpenSearch(w, r)
+	case p == "search":
+		query := r.URL.Query().Get("query")
+		if query == "" {
+			query = r.URL.Query().Get("q")
+		}
+		feed, err := s.Catalog.Search(ctx, query, page)
+		s.serveFeed(w, r, feed, err, s.URL(""))
+	case strings.HasPrefix(p, "browse/"):
+		bp := strings.Trim(strings.TrimPrefix(p, "browse/"), "/")
+		feed, err := s.Catalog.Browse(ctx, bp, page)
+		s.serveFeed(w, r, feed, err, s.upURL(bp))
+	case strings.HasPrefix(p, "publications/"):
+		// the id is unescaped from the raw path, r.URL.Path is already
+		// decoded and lose the escaped slashes of PublicationURL
+		escaped := strings.TrimPrefix(strings.TrimPrefix(r.URL.EscapedPath(), s.Prefix), "/")
+		id, err := url.PathUnescape(strings.TrimPrefix(escaped, "publications/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pub, err := s.Catalog.Publication(ctx, id)
+		s.servePublication(w, r, pub, err)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// URL return the absolute path of a route of the server
+func (s *Server) URL(route string) string {
+	return s.Prefix + "/" + strings.TrimPrefix(route, "/")
+}
+
+// BrowseURL return the url of the feed served by Catalog.Browse for path
+func (s *Server) BrowseURL(p string) string {
+	return s.URL("browse/" + strings.TrimPrefix(p, "/"))
+}
+
+// PublicationURL return the url of the publication document for id
+func (s *Server) PublicationURL(id string) string {
+	return s.URL("publications/" + url.PathEscape(id))
+}
+
+// SearchURL return the templated url of the search endpoint
+func (s *Server) SearchURL() string {
+	return s.URL("search") + "{?query}"
+}
+
+// upURL return the url of the parent of a browse path
+func (s *Server) upURL(p string) string {
+	parent := path.Dir(p)
+	if parent == "." || parent == "/" {
+		return s.URL("")
+	}
+	return s.BrowseURL(parent)
+}
+
+func pageParam(r *http.Request) int {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
+
+func (s *Server) serveError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,96 @@
+// Package rel list the link relations of OPDS, Readium and the IANA
+// registry used in feeds
+package rel
+
+import "strings"
+
+// IANA link relations
+const (
+	Self        = "self"
+	Alternate   = "alternate"
+	Start       = "start"
+	Up          = "up"
+	Next        = "next"
+	Previous    = "previous"
+	First       = "first"
+	Last        = "last"
+	Search      = "search"
+	Related     = "related"
+	Subsection  = "subsection"
+	Collection  = "collection"
+	Help        = "help"
+	License     = "license"
+	Preview     = "preview"
+	Describedby = "describedby"
+	Icon        = "icon"
+	Contents    = "contents"
+	Author      = "author"
+	Publisher   = "publisher"
+	Cover       = "cover"
+	Manifest    = "manifest"
+	Profile     = "profile"
+	Edit        = "edit"
+)
+
+// OPDS acquisition relations
+const (
+	Acquisition           = "http://opds-spec.org/acquisition"
+	AcquisitionOpenAccess = "http://opds-spec.org/acquisition/open-access"
+	AcquisitionBorrow     = "http://opds-spec.org/acquisition/borrow"
+	AcquisitionBuy        = "http://opds-spec.org/acquisition/buy"
+	AcquisitionSample     = "http://opds-spec.org/acquisition/sample"
+	AcquisitionSubscribe  = "http://opds-spec.org/acquisition/subscribe"
+)
+
+// Other OPDS relations
+const (
+	Image                  = "http://opds-spec.org/image"
+	Thumbnail              = "http://opds-spec.org/image/thumbnail"
+	Facet                  = "http://opds-spec.org/facet"
+	Group                  = "http://opds-spec.org/group"
+	Featured               = "http://opds-spec.org/featured"
+	Recommended            = "http://opds-spec.org/recommended"
+	SortNew                = "http://opds-spec.org/sort/new"
+	SortPopular            = "http://opds-spec.org/sort/popular"
+	Shelf                  = "http://opds-spec.org/shelf"
+	Subscriptions          = "http://opds-spec.org/subscriptions"
+	Crawlable              = "http://opds-spec.org/crawlable"
+	AuthenticationDocument = "http://opds-spec.org/auth/document"
+	LCPHint                = "hint"
+	LCPPublication         = "publication"
+	LCPStatus              = "status"
+)
+
+// Acquisitions are the acquisition relations, generic first
+var Acquisitions = []string{
+	Acquisition,
+	AcquisitionOpenAccess,
+	AcquisitionBorrow,
+	AcquisitionBuy,
+	AcquisitionSample,
+	AcquisitionSubscribe,
+}
+
+// IsAcquisition check if r is the generic acquisition relation or one of
+// its subtypes
+func IsAcquisition(r string) bool {
+	return r == Acquisition || strings.HasPrefix(r, Acquisition+"/")
+}
+
+// IsImage check if r is the OPDS image or thumbnail relation
+func IsImage(r string) bool {
+	return r == Image || r == Thumbnail || r == Cover
+}
+
+// Normalize return the registered form of r, the obsolete "prev" is
+// replaced by "previous" and registered relations are lower cased
+func Normalize(r string) string {
+	r = strings.TrimSpace(r)
+	if strings.EqualFold(r, "prev") {
+		return Previous
+	}
+	if !strings.Contains(r, ":") {
+		return strings.ToLower(r)
+	}
+	return r
+}
@@ -0,0 +1,56 @@
+package search
+
+import (
+	"context"
+
+	"github.com/ohzqq/libopds2-go/opds2"
+	"github.com/ohzqq/libopds2-go/opdsserver"
+)
+
+// Feed return the page of the results for query as an OPDS feed, the server
+// adds the links from the pagination metadata
+func (ix *Index) Feed(query string, page int, itemsPerPage int) opds2.Feed {
+	results := ix.Search(query)
+	feed := opds2.New("Search: " + query)
+	feed.Links = opds2.Links{}
+	feed.Metadata.NumberOfItems = len(results)
+
+	start, end := 0, len(results)
+	if itemsPerPage > 0 {
+		if page < 1 {
+			page = 1
+		}
+		start = (page - 1) * itemsPerPage
+		if start > len(results) {
+			start = len(results)
+		}
+		if end = start + itemsPerPage; end > len(results) {
+			end = len(results)
+		}
+		feed.Metadata.ItemsPerPage = itemsPerPage
+		feed.Metadata.CurrentPage = page
+	}
+	for _, r := range results[start:end] {
+		feed.Publications = append(feed.Publications, r.Publication)
+	}
+	return feed
+}
+
+// Catalog answer the searches of an opdsserver.Catalog with an index, the
+// other feeds are served by the wrapped catalog
+type Catalog struct {
+	opdsserver.Catalog
+	Index        *Index
+	ItemsPerPage int
+}
+
+// NewCatalog wrap catalog with the index, 50 results per page
+func NewCatalog(catalog opdsserver.Catalog, index *Index) *Catalog {
+	return &Catalog{Catalog: catalog, Index: index, ItemsPerPage: 50}
+}
+
+// Search implement opdsserver.Catalog
+func (c *Catalog) Search(ctx context.Context, query string, page int) (*opds2.Feed, error) {
+	feed := c.Index.Feed(query, page, c.ItemsPerPage)
+	return &feed, nil
+}
@@ -0,0 +1,298 @@
+// Package search provide an embedded full-text index of publications ranked
+// with BM25, the index can be saved to disk and answer the search link of
+// an OPDS catalog
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ohzqq/libopds2-go/opds2"
+)
+
+// Weights of the fields of a publication, a word in the title counts as
+// three words of the description
+const (
+	WeightTitle       = 3.0
+	WeightContributor = 2.0
+	WeightSeries      = 2.0
+	WeightSubject     = 1.5
+	WeightDescription = 1.0
+)
+
+// maxExpansions limit the number of indexed terms a query word can match by
+// prefix
+const maxExpansions = 50
+
+// Index is an inverted index of publications, it is safe for concurrent use
+type Index struct {
+	// K1 and B are the BM25 parameters
+	K1 float64
+	B  float64
+
+	mu       sync.RWMutex
+	docs     []document
+	ids      map[string]int
+	postings map[string][]posting
+	langs    map[string]bool
+	total    float64
+	count    int
+	vocab    []string
+}
+
+// Result is a publication matching a query with its score
+type Result struct {
+	Publication opds2.Publication
+	Score       float64
+}
+
+type document struct {
+	Publication opds2.Publication
+	Length      float64
+	Removed     bool
+}
+
+type posting struct {
+	Doc  int
+	Freq float64
+}
+
+// New create an empty index
+func New() *Index {
+	return &Index{
+		K1:       1.2,
+		B:        0.75,
+		ids:      make(map[string]int),
+		postings: make(map[string][]posting),
+		langs:    make(map[string]bool),
+	}
+}
+
+// Add index publications, a publication already in the index with the same
+// identifier (or acquisition href) is replaced
+func (ix *Index) Add(pubs ...opds2.Publication) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for _, p := range pubs {
+		key := opds2.PublicationKey(&p)
+		if i, ok := ix.ids[key]; ok && key != "" {
+			ix.remove(i)
+		}
+
+		id := len(ix.docs)
+		freqs, length := ix.terms(&p)
+		for term, f := range freqs {
+			if _, ok := ix.postings[term]; !ok {
+				ix.vocab = nil
+			}
+			ix.postings[term] = append(ix.postings[term], posting{Doc: id, Freq: f})
+		}
+		ix.docs = append(ix.docs, document{Publication: p, Length: length})
+		if key != "" {
+			ix.ids[key] = id
+		}
+		ix.total += length
+		ix.count++
+	}
+}
+
+// Remove drop the publication with the identifier (or acquisition href) id
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if i, ok := ix.ids[id]; ok {
+		ix.remove(i)
+		delete(ix.ids, id)
+	}
+}
+
+func (ix *Index) remove(i int) {
+	d := &ix.docs[i]
+	if d.Removed {
+		return
+	}
+	d.Removed = true
+	ix.total -= d.Length
+	ix.count--
+}
+
+// Len return the number of publications indexed
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.count
+}
+
+// Search return the publications matching every word of the query, best
+// first, the last word of the query also matches as a prefix
+func (ix *Index) Search(query string) []Result {
+	words := Tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	ix.mu.Lock()
+	if ix.vocab == nil {
+		ix.buildVocab()
+	}
+	ix.mu.Unlock()
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var scores map[int]float64
+	for i, w := range words {
+		ws := ix.scoreWord(w, i == len(words)-1)
+		if scores == nil {
+			scores = ws
+			continue
+		}
+		for d, s := range scores {
+			if extra, ok := ws[d]; ok {
+				scores[d] = s + extra
+			} else {
+				delete(scores, d)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for d, s := range scores {
+		results = append(results, Result{Publication: ix.docs[d].Publication, Score: s})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return strings.ToLower(results[i].Publication.Metadata.Title.String()) <
+			strings.ToLower(results[j].Publication.Metadata.Title.String())
+	})
+	return results
+}
+
+// scoreWord return the BM25 score of the documents matching a query word,
+// the word is stemmed for every language of the index and prefix matches
+// count half
+func (ix *Index) scoreWord(word string, prefix bool) map[int]float64 {
+	variants := map[string]float64{word: 1}
+	for lang := range ix.langs {
+		variants[Stem(lang, word)] = 1
+	}
+	if prefix && len([]rune(word)) > 1 {
+		i := sort.SearchStrings(ix.vocab, word)
+		for n := 0; i < len(ix.vocab) && n < maxExpansions && strings.HasPrefix(ix.vocab[i], word); i, n = i+1, n+1 {
+			if _, ok := variants[ix.vocab[i]]; !ok {
+				variants[ix.vocab[i]] = 0.5
+			}
+		}
+	}
+
+	scores := make(map[int]float64)
+	for term, boost := range variants {
+		for d, s := range ix.scoreTerm(term) {
+			if s *= boost; s > scores[d] {
+				scores[d] = s
+			}
+		}
+	}
+	return scores
+}
+
+func (ix *Index) scoreTerm(term string) map[int]float64 {
+	postings := ix.postings[term]
+	df := 0
+	for _, p := range postings {
+		if !ix.docs[p.Doc].Removed {
+			df++
+		}
+	}
+	if df == 0 {
+		return nil
+	}
+
+	n := float64(ix.count)
+	idf := math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+	avg := ix.total / n
+	scores := make(map[int]float64, df)
+	for _, p := range postings {
+		d := ix.docs[p.Doc]
+		if d.Removed {
+			continue
+		}
+		norm := ix.K1 * (1 - ix.B + ix.B*d.Length/avg)
+		scores[p.Doc] = idf * p.Freq * (ix.K1 + 1) / (p.Freq + norm)
+	}
+	return scores
+}
+
+func (ix *Index) buildVocab() {
+	ix.vocab = make([]string, 0, len(ix.postings))
+	for term := range ix.postings {
+		ix.vocab = append(ix.vocab, term)
+	}
+	sort.Strings(ix.vocab)
+}
+
+// terms return the weighted frequencies of the stemmed terms of a
+// publication and its weighted length
+func (ix *Index) terms(p *opds2.Publication) (map[string]float64, float64) {
+	m := &p.Metadata
+	lang := ""
+	if len(m.Language) > 0 {
+		lang = baseLanguage(m.Language[0])
+		ix.langs[lang] = true
+	}
+
+	freqs := make(map[string]float64)
+	length := 0.0
+	add := func(text string, lang string, weight float64) {
+		for _, w := range Tokenize(text) {
+			freqs[Stem(lang, w)] += weight
+			length += weight
+		}
+	}
+
+	add(m.Title.SingleString, lang, WeightTitle)
+	for l, t := range m.Title.MultiString {
+		l = baseLanguage(l)
+		if _, ok := stemmer(l); ok {
+			ix.langs[l] = true
+		}
+		add(t, l, WeightTitle)
+	}
+	for _, cons := range []opds2.Contributors{
+		m.Author, m.Translator, m.Editor, m.Artist, m.Illustrator, m.Letterer, m.Penciler,
+		m.Colorist, m.Inker, m.Narrator, m.Contributor, m.Publisher, m.Imprint,
+	} {
+		for _, c := range cons {
+			add(multiLanguageText(c.Name), "", WeightContributor)
+		}
+	}
+	if m.BelongsTo != nil {
+		for _, cols := range []opds2.Collections{m.BelongsTo.Series, m.BelongsTo.Collection} {
+			for _, c := range cols {
+				if c.Contributor != nil {
+					add(multiLanguageText(c.Name), lang, WeightSeries)
+				}
+			}
+		}
+	}
+	for _, s := range m.Subject {
+		add(s.Name, lang, WeightSubject)
+	}
+	add(m.Description, lang, WeightDescription)
+
+	return freqs, length
+}
+
+func multiLanguageText(m opds2.MultiLanguage) string {
+	parts := []string{m.SingleString}
+	for _, s := range m.MultiString {
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"strings"
+	"sync"
+)
+
+// Stemmer reduce a tokenized word to its stem
+type Stemmer func(word string) string
+
+// stemmersMu guard stemmers, RegisterStemmer may run while an index is
+// searched
+var stemmersMu sync.RWMutex
+
+// stemmers are light suffix stripping stemmers by language, they work on
+// words without diacritics
+var stemmers = map[string]Stemmer{
+	"en": englishStemmer,
+	"fr": suffixStemmer(3, "issements", "issement", "atrices", "ateurs", "ations", "atrice", "ateur", "ation",
+		"ements", "ement", "ments", "ment", "euses", "euse", "ences", "ence", "ances", "ance", "iques", "ique",
+		"ismes", "isme", "istes", "iste", "ites", "ite", "eaux", "eux", "ives", "ive", "ifs", "if", "ees",
+		"ee", "es", "er", "ez", "e", "s", "x"),
+	"de": suffixStemmer(3, "ungen", "ung", "heiten", "heit", "keiten", "keit", "lich", "isch", "ern",
+		"em", "en", "er", "es", "e", "s"),
+	"es": suffixStemmer(3, "amientos", "imientos", "amiento", "imiento", "aciones", "acion", "adoras",
+		"adores", "adora", "ador", "ancias", "ancia", "mente", "idades", "idad", "ables", "ibles", "able",
+		"ible", "istas", "ista", "osos", "osas", "oso", "osa", "es", "os", "as", "a", "o", "e"),
+	"it": suffixStemmer(3, "amente", "azioni", "azione", "mente", "iste", "isti", "ista", "ismo", "ita",
+		"ori", "ore", "ose", "osi", "osa", "oso", "i", "e", "a", "o"),
+	"pt": suffixStemmer(3, "amentos", "imentos", "amento", "imento", "acoes", "acao", "mente", "idades",
+		"idade", "istas", "ista", "osos", "osas", "oso", "osa", "es", "os", "as", "a", "o", "e"),
+}
+
+// RegisterStemmer set the stemmer used for lang, a primary language subtag
+// like "en", it is safe to call while indexes are in use
+func RegisterStemmer(lang string, s Stemmer) {
+	stemmersMu.Lock()
+	defer stemmersMu.Unlock()
+	stemmers[lang] = s
+}
+
+// Stem return the stem of word in lang, the word is returned unchanged when
+// there is no stemmer for the language
+func Stem(lang string, word string) string {
+	if s, ok := stemmer(baseLanguage(lang)); ok {
+		return s(word)
+	}
+	return word
+}
+
+func stemmer(lang string) (Stemmer, bool) {
+	stemmersMu.RLock()
+	defer stemmersMu.RUnlock()
+	s, ok := stemmers[lang]
+	return s, ok
+}
+
+// suffixStemmer strip the first matching suffix, longest first, keeping a
+// stem of at least min letters
+func suffixStemmer(min int, suffixes ...string) Stemmer {
+	return func(word string) string {
+		for _, s := range suffixes {
+			if strings.HasSuffix(word, s) && len(word)-len(s) >= min {
+				return word[:len(word)-len(s)]
+			}
+		}
+		return word
+	}
+}
+
+var englishSuffixes = suffixStemmer(3, "ational", "ization", "fulness", "ousness", "iveness", "ation",
+	"ment", "ness", "ing", "ed", "ly")
+
+// englishStemmer remove plurals with the rules of the S stemmer then strip
+// common suffixes, undoubling the final consonant of "running"
+func englishStemmer(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && !strings.HasSuffix(word, "eies") && !strings.HasSuffix(word, "aies"):
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "es") && !strings.HasSuffix(word, "aes") && !strings.HasSuffix(word, "ees") && !strings.HasSuffix(word, "oes"):
+		word = word[:len(word)-1]
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "ss"):
+		word = word[:len(word)-1]
+	}
+
+	stem := englishSuffixes(word)
+	if stem != word {
+		if n := len(stem); n > 3 && stem[n-1] == stem[n-2] && !strings.ContainsRune("aeiouls", rune(stem[n-1])) {
+			stem = stem[:n-1]
+		}
+	}
+	return stem
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"encoding/gob"
+	"io"
+	"os"
+)
+
+// snapshot is the persisted form of an index
+type snapshot struct {
+	K1, B    float64
+	Docs     []document
+	IDs      map[string]int
+	Postings map[string][]posting
+	Langs    map[string]bool
+	Total    float64
+	Count    int
+}
+
+// Save write the index to w
+func (ix *Index) Save(w io.Writer) error {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return gob.NewEncoder(w).Encode(snapshot{
+		K1:       ix.K1,
+		B:        ix.B,
+		Docs:     ix.docs,
+		IDs:      ix.ids,
+		Postings: ix.postings,
+		Langs:    ix.langs,
+		Total:    ix.total,
+		Count:    ix.count,
+	})
+}
+
+// SaveFile write the index to the file at path
+func (ix *Index) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := ix.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load read an index written by Save
+func Load(r io.Reader) (*Index, error) {
+	var s snapshot
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+
+	ix := New()
+	ix.K1, ix.B = s.K1, s.B
+	ix.docs = s.Docs
+	ix.total, ix.count = s.Total, s.Count
+	if s.IDs != nil {
+		ix.ids = s.IDs
+	}
+	if s.Postings != nil {
+		ix.postings = s.Postings
+	}
+	if s.Langs != nil {
+		ix.langs = s.Langs
+	}
+	return ix, nil
+}
+
+// Open read the index saved in the file at path
+func Open(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}